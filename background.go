@@ -0,0 +1,174 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/gaussdb_exporter/collector"
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"config"
+)
+
+// exporterJob is the name of the background job running the exporter's own
+// queries, next to the jobs of the collectors.
+const exporterJob = "exporter"
+
+// background holds the scheduler of every instance while background
+// scraping is enabled.
+var background = struct {
+	sync.RWMutex
+	schedulers map[string]*collector.Scheduler
+	cancel     context.CancelFunc
+}{}
+
+// getScheduler returns the scheduler of instanceId, or nil if the instance
+// isn't scraped in the background.
+func getScheduler(instanceId string) *collector.Scheduler {
+	background.RLock()
+	defer background.RUnlock()
+	return background.schedulers[instanceId]
+}
+
+// restartBackground stops the schedulers of the previous config and, if
+// background scraping is enabled in cfg, starts one for every instance.
+func restartBackground(cfg *config.Config) {
+	background.Lock()
+	defer background.Unlock()
+
+	if background.cancel != nil {
+		background.cancel()
+		background.cancel = nil
+	}
+	background.schedulers = nil
+	if !cfg.Background.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	background.cancel = cancel
+	background.schedulers = make(map[string]*collector.Scheduler, len(cfg.Instances))
+	for _, instance := range cfg.Instances {
+		instanceId := instance.InstanceId
+		logger := utils.GetLogger().With("instance_id", instanceId)
+
+		dsn, err := getDataSourceById(instanceId)
+		if err != nil {
+			logger.Error("Not scraping instance in the background", "err", err)
+			continue
+		}
+		databases := collector.DatabaseFilter{
+			Exclude: instance.ExcludedDatabases(),
+			Include: instance.IncludedDatabases(),
+		}
+		getPool := func(ctx context.Context) (*pool, error) {
+			dsn, err := getDataSourceById(instanceId)
+			if err != nil {
+				return nil, err
+			}
+			return connections.Get(ctx, instanceId, dsn)
+		}
+
+		p, err := collector.NewPostgresCollector(dsn, nil,
+			collector.WithLogger(logger),
+			collector.WithOverrides(collector.Overrides{
+				Enable:  instance.Collectors.Enable,
+				Disable: instance.Collectors.Disable,
+			}),
+			collector.WithDatabases(databases),
+			collector.WithConnector(func(ctx context.Context) (*sql.DB, func(), error) {
+				pool, err := getPool(ctx)
+				if err != nil {
+					return nil, nil, err
+				}
+				return pool.db, pool.Release, nil
+			}),
+			// The connector has just checked the pool, whose server
+			// caches the version.
+			collector.WithVersion(func(ctx context.Context) (semver.Version, error) {
+				pool := connections.Lookup(instanceId)
+				if pool == nil {
+					return semver.Version{}, errors.New("no connection pool")
+				}
+				defer pool.Release()
+				version, _, err := pool.server.Version(ctx)
+				return version, err
+			}),
+		)
+		if err != nil {
+			logger.Error("Not scraping instance in the background", "err", err)
+			continue
+		}
+
+		s := collector.NewScheduler(p,
+			collector.SchedulerWithIntervals(cfg.Background.Interval, cfg.Background.Intervals),
+			collector.SchedulerWithTimeouts(cfg.Collector.Timeout, cfg.Collector.Timeouts),
+		)
+		s.AddJob(exporterJob, func(ctx context.Context, ch chan<- prometheus.Metric) {
+			opts := []ExporterOpt{
+				DisableDefaultMetrics(*disableDefaultMetrics),
+				DisableSettingsMetrics(*disableSettingsMetrics),
+				WithDatabases(databases),
+				WithLogger(logger),
+				WithContext(ctx),
+			}
+			dsn := dsn
+			begun := time.Now()
+			pool, err := getPool(ctx)
+			recordConnect(instanceId, time.Since(begun), err)
+			if err != nil {
+				logger.Error("Connection pool health check failed", "err", err)
+			} else {
+				defer pool.Release()
+				dsn = pool.dsn
+				opts = append(opts, WithServer(dsn, pool.server))
+			}
+			exporter := NewExporter([]string{dsn}, opts...)
+			defer exporter.servers.Close()
+			exporter.Collect(ch)
+		})
+
+		background.schedulers[instanceId] = s
+		go s.Run(ctx)
+		logger.Info("Scraping instance in the background")
+	}
+}
+
+// gatherBackground returns the results of the last runs of the collectors
+// of s that a probe with params asked for, labeled like runProbe labels
+// those of instanceId.
+func gatherBackground(s *collector.Scheduler, params url.Values, instanceId string, logger *utils.Logger) (prometheus.Gatherer, error) {
+	c, err := s.Filter(params["collect[]"], params["exclude[]"])
+	if err != nil {
+		logger.Error("Error filtering background results", "err", err)
+		return nil, err
+	}
+
+	registry := prometheus.NewRegistry()
+	prometheus.WrapRegistererWith(prometheus.Labels{"instance_id": instanceId}, registry).MustRegister(c)
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := registry.Gather()
+		addLabels(families, instanceLabels(instanceId), logger)
+		return families, err
+	}), nil
+}
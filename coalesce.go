@@ -0,0 +1,174 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Sources of the result of a probe, as counted by probeRequests.
+const (
+	sourceExecuted  = "executed"
+	sourceCoalesced = "coalesced"
+	sourceCached    = "cached"
+)
+
+var (
+	// probes coalesces the probes of all requests.
+	probes = newProbeGroup()
+
+	probeRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: exporter,
+		Name:      "probe_requests_total",
+		Help:      "Total number of probe requests by instance_id and whether they ran a collection, shared one in flight (coalesced) or were served from the cache.",
+	}, []string{"instance_id", "source"})
+)
+
+func init() {
+	exporterRegistry.MustRegister(probeRequests)
+}
+
+// probeKey identifies the probes that can share a result: the same instance
+// with the same collect[] and exclude[] filters, in any order.
+func probeKey(instanceId string, collect, exclude []string) string {
+	sorted := func(names []string) string {
+		names = append([]string(nil), names...)
+		sort.Strings(names)
+		return strings.Join(names, ",")
+	}
+	return strings.Join([]string{instanceId, sorted(collect), sorted(exclude)}, "\x00")
+}
+
+// probeResult is the outcome of a collection. families is nil if the probe
+// couldn't be set up, in which case err says why.
+type probeResult struct {
+	families []*dto.MetricFamily
+	err      error
+	done     time.Time
+}
+
+// errProbePanicked is the result shared with waiters of a probe that
+// panicked.
+var errProbePanicked = errors.New("probe panicked")
+
+// probeCall is a collection in flight. It runs under its own context,
+// cancelled once every request waiting for it has gone.
+type probeCall struct {
+	done    chan struct{}
+	result  probeResult
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// probeGroup runs at most one collection per key at a time, the way
+// golang.org/x/sync/singleflight does: callers arriving while one is in
+// flight wait for it and share its result. Successful results can also be
+// reused for a while after they complete.
+type probeGroup struct {
+	mtx   sync.Mutex
+	calls map[string]*probeCall
+	cache map[string]probeResult
+}
+
+func newProbeGroup() *probeGroup {
+	return &probeGroup{
+		calls: make(map[string]*probeCall),
+		cache: make(map[string]probeResult),
+	}
+}
+
+// Do returns the result of fn for key, and whether it was executed for this
+// call, coalesced with a call in flight or served from the cache. Results
+// without errors are cached for ttl, if positive.
+//
+// fn runs under a context that keeps the values of ctx but is only cancelled
+// when the contexts of all the callers sharing it are done. The last caller
+// to leave waits for fn to return and gets what it collected so far; the
+// others get the error of their context.
+func (g *probeGroup) Do(ctx context.Context, key string, ttl time.Duration, fn func(context.Context) ([]*dto.MetricFamily, error)) (probeResult, string) {
+	g.mtx.Lock()
+	if cached, ok := g.cache[key]; ok {
+		if time.Since(cached.done) < ttl {
+			g.mtx.Unlock()
+			return cached, sourceCached
+		}
+		delete(g.cache, key)
+	}
+	source := sourceCoalesced
+	c, ok := g.calls[key]
+	if !ok {
+		source = sourceExecuted
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &probeCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+		go g.run(callCtx, key, ttl, c, fn)
+	}
+	c.waiters++
+	g.mtx.Unlock()
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		g.mtx.Lock()
+		c.waiters--
+		last := c.waiters == 0
+		if last && g.calls[key] == c {
+			// Later callers start a collection of their own.
+			delete(g.calls, key)
+		}
+		g.mtx.Unlock()
+		if !last {
+			return probeResult{err: ctx.Err(), done: time.Now()}, source
+		}
+		c.cancel()
+		<-c.done
+	}
+	return c.result, source
+}
+
+// run runs fn for the call c and releases its waiters.
+func (g *probeGroup) run(ctx context.Context, key string, ttl time.Duration, c *probeCall, fn func(context.Context) ([]*dto.MetricFamily, error)) {
+	// Release the waiters and the key even if fn panics.
+	c.result.err = errProbePanicked
+	defer func() {
+		if r := recover(); r != nil {
+			utils.GetLogger().Error("Probe panicked", "panic", r)
+		}
+		g.mtx.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		// A collection cut short may lack metrics without reporting an
+		// error, so it isn't reused.
+		if ttl > 0 && c.result.err == nil && ctx.Err() == nil {
+			g.cache[key] = c.result
+		}
+		g.mtx.Unlock()
+		c.cancel()
+		close(c.done)
+	}()
+
+	families, err := fn(ctx)
+	c.result = probeResult{families: families, err: err, done: time.Now()}
+}
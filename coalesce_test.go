@@ -0,0 +1,136 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestProbeGroup(t *testing.T) {
+	g := newProbeGroup()
+	release := make(chan struct{})
+	calls := 0
+	fn := func(context.Context) ([]*dto.MetricFamily, error) {
+		calls++
+		<-release
+		return []*dto.MetricFamily{}, nil
+	}
+
+	// The first call runs fn; the second arrives while it is in flight.
+	sources := make([]string, 2)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, sources[0] = g.Do(context.Background(), "a", time.Minute, fn)
+	}()
+	for {
+		g.mtx.Lock()
+		_, inFlight := g.calls["a"]
+		g.mtx.Unlock()
+		if inFlight {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, sources[1] = g.Do(context.Background(), "a", time.Minute, fn)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected fn to run once, ran %d times", calls)
+	}
+	if sources[0] != sourceExecuted || sources[1] != sourceCoalesced {
+		t.Errorf("expected executed and coalesced, got %v", sources)
+	}
+
+	if _, source := g.Do(context.Background(), "a", time.Minute, fn); source != sourceCached {
+		t.Errorf("expected a cached result, got %s", source)
+	}
+	if _, source := g.Do(context.Background(), "a", 0, fn); source != sourceExecuted || calls != 2 {
+		t.Errorf("expected fn to run again without a ttl, got %s", source)
+	}
+}
+
+func TestProbeGroupCancel(t *testing.T) {
+	g := newProbeGroup()
+	started := make(chan struct{})
+	fn := func(ctx context.Context) ([]*dto.MetricFamily, error) {
+		close(started)
+		<-ctx.Done()
+		return []*dto.MetricFamily{}, nil
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	results := make(chan probeResult, 2)
+	go func() {
+		result, _ := g.Do(first, "a", time.Minute, fn)
+		results <- result
+	}()
+	<-started
+	go func() {
+		result, _ := g.Do(second, "a", time.Minute, fn)
+		results <- result
+	}()
+	for {
+		g.mtx.Lock()
+		waiters := g.calls["a"].waiters
+		g.mtx.Unlock()
+		if waiters == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The probe keeps running for the caller still waiting.
+	cancelFirst()
+	if result := <-results; result.err != context.Canceled {
+		t.Errorf("expected the first caller to get its context's error, got %v", result.err)
+	}
+	select {
+	case <-results:
+		t.Fatal("expected the probe to keep running for the second caller")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Once the last caller is gone it is cancelled, and that caller gets
+	// what was collected.
+	cancelSecond()
+	if result := <-results; result.err != nil || result.families == nil {
+		t.Errorf("expected the last caller to get the collected families, got %+v", result)
+	}
+	if _, cached := g.cache["a"]; cached {
+		t.Error("expected a cancelled probe not to be cached")
+	}
+}
+
+func TestProbeKey(t *testing.T) {
+	if probeKey("a", []string{"locks", "wal"}, nil) != probeKey("a", []string{"wal", "locks"}, nil) {
+		t.Error("expected the order of collect[] not to matter")
+	}
+	if probeKey("a", []string{"locks"}, nil) == probeKey("a", nil, []string{"locks"}) {
+		t.Error("expected collect[] and exclude[] to be told apart")
+	}
+}
@@ -1,222 +1,445 @@
-// Copyright 2022 The Prometheus Authors
-// Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this file except in compliance with the License.
-// You may obtain a copy of the License at
-//
-// http://www.apache.org/licenses/LICENSE-2.0
-//
-// Unless required by applicable law or agreed to in writing, software
-// distributed under the License is distributed on an "AS IS" BASIS,
-// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-// See the License for the specific language governing permissions and
-// limitations under the License.
-
-package collector
-
-import (
-	"context"
-	"errors"
-	"fmt"
-	"sync"
-	"time"
-
-	"github.com/alecthomas/kingpin/v2"
-	"github.com/go-kit/log"
-	"github.com/prometheus-community/gaussdb_exporter/utils"
-	"github.com/prometheus/client_golang/prometheus"
-)
-
-var (
-	factories              = make(map[string]func() (Collector, error))
-	initiatedCollectorsMtx = sync.Mutex{}
-	initiatedCollectors    = make(map[string]Collector)
-	collectorState         = make(map[string]*bool)
-	forcedCollectors       = map[string]bool{} // collectors which have been explicitly enabled or disabled
-)
-
-const (
-	// Namespace for all metrics.
-	//namespace = "pg"
-	namespace = "gs"
-
-	defaultEnabled  = true
-	defaultDisabled = false
-)
-
-var (
-	scrapeDurationDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "scrape", "collector_duration_seconds"),
-		"gaussdb_exporter: Duration of a collector scrape.",
-		[]string{"collector"},
-		nil,
-	)
-	scrapeSuccessDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "scrape", "collector_success"),
-		"gaussdb_exporter: Whether a collector succeeded.",
-		[]string{"collector"},
-		nil,
-	)
-)
-
-type Collector interface {
-	Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error
-}
-
-type collectorConfig struct {
-	logger           log.Logger
-	excludeDatabases []string
-}
-
-func registerCollector(name string, isDefaultEnabled bool, createFunc func() (Collector, error)) {
-
-	var helpDefaultState string
-	if isDefaultEnabled {
-		helpDefaultState = "enabled"
-	} else {
-		helpDefaultState = "disabled"
-	}
-
-	// Create flag for this collector
-	flagName := fmt.Sprintf("collector.%s", name)
-	flagHelp := fmt.Sprintf("Enable the %s collector (default: %s).", name, helpDefaultState)
-	defaultValue := fmt.Sprintf("%v", isDefaultEnabled)
-
-	//获取命令行参数值collector.XXXX, flag的结果为true/false
-	flag := kingpin.Flag(flagName, flagHelp).Default(defaultValue).Action(collectorFlagAction(name)).Bool()
-	//确认是否收集该类型的指标
-	collectorState[name] = flag
-
-	// Register the create function for this collector
-	factories[name] = createFunc
-}
-
-// PostgresCollector implements the prometheus.Collector interface.
-type PostgresCollector struct {
-	Collectors map[string]Collector
-	instance   *instance
-}
-
-type Option func(*PostgresCollector) error
-
-// NewPostgresCollector creates a new PostgresCollector.
-func NewPostgresCollector(dsn string, filters []string) (*PostgresCollector, error) {
-	p := &PostgresCollector{}
-
-	f := make(map[string]bool)
-	for _, filter := range filters {
-		enabled, exist := collectorState[filter]
-		if !exist {
-			return nil, fmt.Errorf("missing collector: %s", filter)
-		}
-		if !*enabled {
-			return nil, fmt.Errorf("disabled collector: %s", filter)
-		}
-		f[filter] = true
-	}
-	collectors := make(map[string]Collector)
-	initiatedCollectorsMtx.Lock()
-	defer initiatedCollectorsMtx.Unlock()
-	//collectorState表示需要收集指标的类型 例如bgwriter\wal\database\lock等类型的监控指标
-	for key, enabled := range collectorState {
-		if !*enabled || (len(f) > 0 && !f[key]) {
-			continue
-		}
-		if collector, ok := initiatedCollectors[key]; ok {
-			collectors[key] = collector
-		} else {
-			//
-			collector, err := factories[key]()
-			if err != nil {
-				return nil, err
-			}
-			collectors[key] = collector
-			initiatedCollectors[key] = collector
-		}
-	}
-
-	p.Collectors = collectors
-
-	if dsn == "" {
-		return nil, errors.New("empty dsn")
-	}
-
-	instance, err := newInstance(dsn)
-	if err != nil {
-		return nil, err
-	}
-	p.instance = instance
-
-	return p, nil
-}
-
-// Describe implements the prometheus.Collector interface.
-func (p PostgresCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- scrapeDurationDesc
-	ch <- scrapeSuccessDesc
-}
-
-// Collect implements the prometheus.Collector interface.
-func (p PostgresCollector) Collect(ch chan<- prometheus.Metric) {
-	ctx := context.TODO()
-
-	// copy the instance so that concurrent scrapes have independent instances
-	inst := p.instance.copy()
-
-	// Set up the database connection for the collector.
-	err := inst.setup()
-	if err != nil {
-		utils.GetLogger().Error("Error opening connection to database", "err", err)
-		return
-	}
-	defer inst.Close()
-
-	wg := sync.WaitGroup{}
-	wg.Add(len(p.Collectors))
-	for name, c := range p.Collectors {
-		go func(name string, c Collector) {
-			execute(ctx, name, c, inst, ch)
-			wg.Done()
-		}(name, c)
-	}
-	wg.Wait()
-}
-
-func execute(ctx context.Context, name string, c Collector, instance *instance, ch chan<- prometheus.Metric) {
-	begin := time.Now()
-	err := c.Update(ctx, instance, ch)
-	duration := time.Since(begin)
-	var success float64
-
-	if err != nil {
-		if IsNoDataError(err) {
-			utils.GetLogger().Debug("collector returned no data", "name", name, "duration_seconds", duration.Seconds(), "err", err)
-		} else {
-			utils.GetLogger().Error("collector failed", "name", name, "duration_seconds", duration.Seconds(), "err", err)
-		}
-		success = 0
-	} else {
-		utils.GetLogger().Debug("collector succeeded", "name", name, "duration_seconds", duration.Seconds())
-		success = 1
-	}
-	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), name)
-	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, name)
-}
-
-// collectorFlagAction generates a new action function for the given collector
-// to track whether it has been explicitly enabled or disabled from the command line.
-// A new action function is needed for each collector flag because the ParseContext
-// does not contain information about which flag called the action.
-// See: https://github.com/alecthomas/kingpin/issues/294
-func collectorFlagAction(collector string) func(ctx *kingpin.ParseContext) error {
-	return func(ctx *kingpin.ParseContext) error {
-		forcedCollectors[collector] = true
-		return nil
-	}
-}
-
-// ErrNoData indicates the collector found no data to collect, but had no other error.
-var ErrNoData = errors.New("collector returned no data")
-
-func IsNoDataError(err error) bool {
-	return err == ErrNoData
-}
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	factories              = make(map[string]func() (Collector, error))
+	initiatedCollectorsMtx = sync.Mutex{}
+	initiatedCollectors    = make(map[string]Collector)
+	collectorState         = make(map[string]*bool)
+	forcedCollectors       = map[string]bool{} // collectors which have been explicitly enabled or disabled
+)
+
+const (
+	// Namespace for all metrics.
+	//namespace = "pg"
+	namespace = "gs"
+
+	defaultEnabled  = true
+	defaultDisabled = false
+)
+
+// collectorTimeout is the default limit for the run time of each collector.
+var collectorTimeout = kingpin.Flag("collector.timeout", "Maximum time each collector may run before its query is cancelled, 0 for no limit besides the scrape timeout. Each collector holds a connection of its own, so at most --db.max-open-conns run at a time, and the timeout only starts once a collector has a connection. Overridden by collector.timeout in config.yml.").Default("0s").Duration()
+
+var (
+	scrapeDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_duration_seconds"),
+		"gaussdb_exporter: Duration of a collector scrape.",
+		[]string{"collector"},
+		nil,
+	)
+	scrapeSuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_success"),
+		"gaussdb_exporter: Whether a collector succeeded.",
+		[]string{"collector"},
+		nil,
+	)
+	scrapeTimeoutDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_timeout"),
+		"gaussdb_exporter: Whether a collector was cancelled because it ran out of time.",
+		[]string{"collector"},
+		nil,
+	)
+)
+
+type Collector interface {
+	Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error
+}
+
+type collectorConfig struct {
+	logger *utils.Logger
+}
+
+func registerCollector(name string, isDefaultEnabled bool, createFunc func() (Collector, error)) {
+
+	var helpDefaultState string
+	if isDefaultEnabled {
+		helpDefaultState = "enabled"
+	} else {
+		helpDefaultState = "disabled"
+	}
+
+	// Create flag for this collector
+	flagName := fmt.Sprintf("collector.%s", name)
+	flagHelp := fmt.Sprintf("Enable the %s collector (default: %s).", name, helpDefaultState)
+	defaultValue := fmt.Sprintf("%v", isDefaultEnabled)
+
+	//获取命令行参数值collector.XXXX, flag的结果为true/false
+	flag := kingpin.Flag(flagName, flagHelp).Default(defaultValue).Action(collectorFlagAction(name)).Bool()
+	//确认是否收集该类型的指标
+	collectorState[name] = flag
+
+	// Register the create function for this collector
+	factories[name] = createFunc
+}
+
+// PostgresCollector implements the prometheus.Collector interface.
+type PostgresCollector struct {
+	Collectors map[string]Collector
+	instance   *instance
+	overrides  Overrides
+
+	ctx      context.Context
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+}
+
+type Option func(*PostgresCollector) error
+
+// WithContext sets the context the collectors run their queries under. When
+// it is done, running queries are cancelled and Collect returns.
+func WithContext(ctx context.Context) Option {
+	return func(p *PostgresCollector) error {
+		p.ctx = ctx
+		return nil
+	}
+}
+
+// WithTimeouts overrides the --collector.timeout flag with timeout, if
+// positive, and with timeouts for the named collectors.
+func WithTimeouts(timeout time.Duration, timeouts map[string]time.Duration) Option {
+	return func(p *PostgresCollector) error {
+		if timeout > 0 {
+			p.timeout = timeout
+		}
+		p.timeouts = timeouts
+		return nil
+	}
+}
+
+// WithLogger sets the logger the collectors log through.
+func WithLogger(logger *utils.Logger) Option {
+	return func(p *PostgresCollector) error {
+		p.instance.logger = logger
+		return nil
+	}
+}
+
+// WithConnector makes every collection get its database handle from
+// connect, typically a pool shared across collections, instead of opening
+// one of its own. The handle is not closed after use; the function returned
+// along with it is called instead.
+func WithConnector(connect func(ctx context.Context) (*sql.DB, func(), error)) Option {
+	return func(p *PostgresCollector) error {
+		p.instance.connect = connect
+		return nil
+	}
+}
+
+// WithVersion makes every collection get the server version from version,
+// which may cache it, instead of querying it.
+func WithVersion(version func(context.Context) (semver.Version, error)) Option {
+	return func(p *PostgresCollector) error {
+		p.instance.versionFunc = version
+		return nil
+	}
+}
+
+// WithOverrides turns collectors on or off for this instance.
+func WithOverrides(o Overrides) Option {
+	return func(p *PostgresCollector) error {
+		p.overrides = o
+		return nil
+	}
+}
+
+// WithDatabases limits the per-database metrics to the databases f covers.
+func WithDatabases(f DatabaseFilter) Option {
+	return func(p *PostgresCollector) error {
+		p.instance.databases = f
+		return nil
+	}
+}
+
+// NewPostgresCollector creates a new PostgresCollector.
+func NewPostgresCollector(dsn string, filters []string, options ...Option) (*PostgresCollector, error) {
+	p := &PostgresCollector{
+		ctx:     context.Background(),
+		timeout: *collectorTimeout,
+	}
+
+	if dsn == "" {
+		return nil, errors.New("empty dsn")
+	}
+
+	instance, err := newInstance(dsn)
+	if err != nil {
+		return nil, err
+	}
+	p.instance = instance
+
+	for _, option := range options {
+		if err := option(p); err != nil {
+			return nil, err
+		}
+	}
+
+	collectors, err := selectCollectors(filters, nil, p.overrides)
+	if err != nil {
+		return nil, err
+	}
+	p.Collectors = collectors
+
+	return p, nil
+}
+
+// Describe implements the prometheus.Collector interface.
+func (p PostgresCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- scrapeDurationDesc
+	ch <- scrapeSuccessDesc
+	ch <- scrapeTimeoutDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (p PostgresCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := p.ctx
+
+	// copy the instance so that concurrent scrapes have independent instances
+	inst := p.instance.copy()
+
+	// Set up the database connection for the collector.
+	err := inst.setup(ctx)
+	if err != nil {
+		inst.logger.Error("Error opening connection to database", "err", err)
+		return
+	}
+	defer inst.Close()
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(p.Collectors))
+	for name, c := range p.Collectors {
+		go func(name string, c Collector) {
+			execute(ctx, name, c, inst, p.timeoutFor(name), ch)
+			wg.Done()
+		}(name, c)
+	}
+	wg.Wait()
+}
+
+// timeoutFor returns the timeout of the named collector.
+func (p PostgresCollector) timeoutFor(name string) time.Duration {
+	if timeout, ok := p.timeouts[name]; ok {
+		return timeout
+	}
+	return p.timeout
+}
+
+// execute runs c on a connection of its own, so that its timeout only
+// counts its own queries. When the timeout, if positive, passes or ctx is
+// done, the driver cancels the running query on the server and the other
+// collectors carry on.
+func execute(ctx context.Context, name string, c Collector, instance *instance, timeout time.Duration, ch chan<- prometheus.Metric) {
+	logger := instance.logger.With("collector", name)
+	begin := time.Now()
+
+	var success, timedOut float64
+	err := func() error {
+		conn, err := instance.db.Conn(ctx)
+		if err != nil {
+			// Starved of a connection by slower collectors until the
+			// scrape ran out of time.
+			if ctx.Err() != nil {
+				timedOut = 1
+			}
+			return err
+		}
+		defer conn.Close()
+
+		// Only start the clock once the collector has a connection.
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		err = c.Update(ctx, instance.withConn(conn, logger), ch)
+		if err != nil && ctx.Err() != nil {
+			timedOut = 1
+		}
+		return err
+	}()
+	duration := time.Since(begin)
+
+	if err != nil {
+		switch {
+		case IsNoDataError(err):
+			logger.Debug("collector returned no data", "duration_seconds", duration.Seconds(), "err", err)
+		case timedOut == 1:
+			// The query was cancelled because the collector or the scrape
+			// ran out of time or Prometheus went away, not because it failed.
+			logger.Error("collector failed", "reason", "timeout", "timeout", timeout, "duration_seconds", duration.Seconds(), "err", err)
+		default:
+			logger.Error("collector failed", "reason", "error", "duration_seconds", duration.Seconds(), "err", err)
+		}
+		success = 0
+	} else {
+		logger.Debug("collector succeeded", "duration_seconds", duration.Seconds())
+		success = 1
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, duration.Seconds(), name)
+	ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, success, name)
+	ch <- prometheus.MustNewConstMetric(scrapeTimeoutDesc, prometheus.GaugeValue, timedOut, name)
+}
+
+// Names returns the names of all collectors, whether enabled or not.
+func Names() []string {
+	names := make([]string, 0, len(collectorState))
+	for name := range collectorState {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Overrides turns collectors on or off for one instance, whatever their
+// --collector.<name> flags say.
+type Overrides struct {
+	Enable  []string
+	Disable []string
+}
+
+// DefaultExcludeDatabases are left out of the per-database metrics unless
+// a DatabaseFilter includes them explicitly.
+var DefaultExcludeDatabases = []string{"template0", "template1", "security"}
+
+// DatabaseFilter picks the databases of one instance that per-database
+// metrics are collected for. Exclude always wins. Otherwise a non-empty
+// Include names the only databases collected for, and can bring back any of
+// DefaultExcludeDatabases; without it, every database but those is.
+type DatabaseFilter struct {
+	// Exclude names databases left out in addition to
+	// DefaultExcludeDatabases.
+	Exclude []string
+	// Include, if not empty, names the only databases collected for.
+	Include []string
+}
+
+// Skip reports whether the metrics of the database datname are left out.
+func (f DatabaseFilter) Skip(datname string) bool {
+	if sliceContains(f.Exclude, datname) {
+		return true
+	}
+	if len(f.Include) > 0 {
+		return !sliceContains(f.Include, datname)
+	}
+	return sliceContains(DefaultExcludeDatabases, datname)
+}
+
+// selectCollectors returns the enabled collectors, limited to the ones named
+// in collect if it isn't empty and without the ones named in exclude. The
+// collectors in o.Enable count as enabled; those in o.Disable are left out
+// unless named in collect. Naming a collector that doesn't exist or is
+// disabled is an error wrapping ErrUnknownCollector or ErrDisabledCollector.
+func selectCollectors(collect, exclude []string, o Overrides) (map[string]Collector, error) {
+	enabled := make(map[string]bool, len(collectorState))
+	for name, flag := range collectorState {
+		enabled[name] = *flag
+	}
+	for _, name := range o.Enable {
+		if _, exist := enabled[name]; !exist {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCollector, name)
+		}
+		enabled[name] = true
+	}
+
+	include := make(map[string]bool)
+	for _, name := range collect {
+		on, exist := enabled[name]
+		if !exist {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCollector, name)
+		}
+		if !on {
+			return nil, fmt.Errorf("%w: %s", ErrDisabledCollector, name)
+		}
+		include[name] = true
+	}
+	skip := make(map[string]bool)
+	for _, name := range exclude {
+		if _, exist := enabled[name]; !exist {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCollector, name)
+		}
+		skip[name] = true
+	}
+	for _, name := range o.Disable {
+		if _, exist := enabled[name]; !exist {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCollector, name)
+		}
+		if len(collect) == 0 {
+			skip[name] = true
+		}
+	}
+
+	collectors := make(map[string]Collector)
+	initiatedCollectorsMtx.Lock()
+	defer initiatedCollectorsMtx.Unlock()
+	//collectorState表示需要收集指标的类型 例如bgwriter\wal\database\lock等类型的监控指标
+	for key, on := range enabled {
+		if !on || (len(include) > 0 && !include[key]) || skip[key] {
+			continue
+		}
+		if collector, ok := initiatedCollectors[key]; ok {
+			collectors[key] = collector
+		} else {
+			collector, err := factories[key]()
+			if err != nil {
+				return nil, err
+			}
+			collectors[key] = collector
+			initiatedCollectors[key] = collector
+		}
+	}
+	return collectors, nil
+}
+
+// collectorFlagAction generates a new action function for the given collector
+// to track whether it has been explicitly enabled or disabled from the command line.
+// A new action function is needed for each collector flag because the ParseContext
+// does not contain information about which flag called the action.
+// See: https://github.com/alecthomas/kingpin/issues/294
+func collectorFlagAction(collector string) func(ctx *kingpin.ParseContext) error {
+	return func(ctx *kingpin.ParseContext) error {
+		forcedCollectors[collector] = true
+		return nil
+	}
+}
+
+// ErrUnknownCollector is returned when a filter names a collector that
+// doesn't exist.
+var ErrUnknownCollector = errors.New("unknown collector")
+
+// ErrDisabledCollector is returned when a filter names a collector that has
+// been disabled on the command line.
+var ErrDisabledCollector = errors.New("disabled collector")
+
+// ErrNoData indicates the collector found no data to collect, but had no other error.
+var ErrNoData = errors.New("collector returned no data")
+
+func IsNoDataError(err error) bool {
+	return err == ErrNoData
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alecthomas/kingpin/v2"
+)
+
+func TestSelectCollectors(t *testing.T) {
+	// Apply the flag defaults so that default enabled collectors are enabled.
+	if _, err := kingpin.CommandLine.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := selectCollectors(nil, nil, Overrides{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := all["locks"]; !ok {
+		t.Fatal("expected the locks collector to be enabled by default")
+	}
+
+	only, err := selectCollectors([]string{"locks", "wait_events"}, nil, Overrides{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(only) != 2 {
+		t.Errorf("expected 2 collectors, got %d", len(only))
+	}
+
+	rest, err := selectCollectors(nil, []string{"locks"}, Overrides{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rest["locks"]; ok || len(rest) != len(all)-1 {
+		t.Errorf("expected every collector but locks, got %d", len(rest))
+	}
+
+	if _, err := selectCollectors([]string{"nope"}, nil, Overrides{}); !errors.Is(err, ErrUnknownCollector) {
+		t.Errorf("expected ErrUnknownCollector, got %v", err)
+	}
+	if _, err := selectCollectors(nil, []string{"nope"}, Overrides{}); !errors.Is(err, ErrUnknownCollector) {
+		t.Errorf("expected ErrUnknownCollector, got %v", err)
+	}
+	if _, err := selectCollectors([]string{"stat_user_tables"}, nil, Overrides{}); !errors.Is(err, ErrDisabledCollector) {
+		t.Errorf("expected ErrDisabledCollector, got %v", err)
+	}
+
+	o := Overrides{Enable: []string{"stat_user_tables"}, Disable: []string{"locks"}}
+	overridden, err := selectCollectors(nil, nil, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := overridden["stat_user_tables"]; !ok {
+		t.Error("expected stat_user_tables to be enabled for the instance")
+	}
+	if _, ok := overridden["locks"]; ok {
+		t.Error("expected locks to be disabled for the instance")
+	}
+	// collect[] overrides the instance's selection.
+	picked, err := selectCollectors([]string{"locks", "stat_user_tables"}, nil, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(picked) != 2 {
+		t.Errorf("expected 2 collectors, got %d", len(picked))
+	}
+	if _, err := selectCollectors(nil, nil, Overrides{Disable: []string{"nope"}}); !errors.Is(err, ErrUnknownCollector) {
+		t.Errorf("expected ErrUnknownCollector, got %v", err)
+	}
+}
+
+func TestDatabaseFilterSkip(t *testing.T) {
+	for _, tc := range []struct {
+		filter  DatabaseFilter
+		datname string
+		want    bool
+	}{
+		{DatabaseFilter{}, "template0", true},
+		{DatabaseFilter{}, "security", true},
+		{DatabaseFilter{}, "school", false},
+		{DatabaseFilter{Exclude: []string{"school"}}, "school", true},
+		{DatabaseFilter{Exclude: []string{"school"}}, "template1", true},
+		{DatabaseFilter{Exclude: []string{"school"}}, "postgres", false},
+		{DatabaseFilter{Include: []string{"school"}}, "school", false},
+		{DatabaseFilter{Include: []string{"school"}}, "postgres", true},
+		{DatabaseFilter{Include: []string{"school"}}, "template0", true},
+		{DatabaseFilter{Include: []string{"template1"}}, "template1", false},
+		{DatabaseFilter{Exclude: []string{"postgres"}, Include: []string{"school", "postgres"}}, "postgres", true},
+	} {
+		if got := tc.filter.Skip(tc.datname); got != tc.want {
+			t.Errorf("%+v.Skip(%q) = %v, want %v", tc.filter, tc.datname, got, tc.want)
+		}
+	}
+}
@@ -1,107 +1,178 @@
-// Copyright 2023 The Prometheus Authors
-// Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this file except in compliance with the License.
-// You may obtain a copy of the License at
-//
-// http://www.apache.org/licenses/LICENSE-2.0
-//
-// Unless required by applicable law or agreed to in writing, software
-// distributed under the License is distributed on an "AS IS" BASIS,
-// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-// See the License for the specific language governing permissions and
-// limitations under the License.
-
-package collector
-
-import (
-	"database/sql"
-	"fmt"
-	"regexp"
-
-	_ "gitee.com/opengauss/openGauss-connector-go-pq"
-	"github.com/blang/semver/v4"
-)
-
-type instance struct {
-	dsn     string
-	db      *sql.DB
-	version semver.Version
-}
-
-func newInstance(dsn string) (*instance, error) {
-	i := &instance{
-		dsn: dsn,
-	}
-
-	// "Create" a database handle to verify the DSN provided is valid.
-	// Open is not guaranteed to create a connection.
-	db, err := sql.Open("opengauss", dsn)
-	if err != nil {
-		return nil, err
-	}
-	db.Close()
-
-	return i, nil
-}
-
-// copy returns a copy of the instance.
-func (i *instance) copy() *instance {
-	return &instance{
-		dsn: i.dsn,
-	}
-}
-
-func (i *instance) setup() error {
-	db, err := sql.Open("opengauss", i.dsn)
-	if err != nil {
-		return err
-	}
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
-	i.db = db
-
-	version, err := queryVersion(i.db)
-	if err != nil {
-		return fmt.Errorf("error querying gauss version: %w", err)
-	} else {
-		i.version = version
-	}
-	return nil
-}
-
-func (i *instance) getDB() *sql.DB {
-	return i.db
-}
-
-func (i *instance) Close() error {
-	return i.db.Close()
-}
-
-// Regex used to get the "short-version" from the postgres version field.
-// The result of SELECT version() is something like "PostgreSQL 9.6.2 on x86_64-pc-linux-gnu, compiled by gcc (GCC) 6.2.1 20160830, 64-bit"
-var versionRegex = regexp.MustCompile(`^\w+ ((\d+)(\.\d+)?(\.\d+)?)`)
-var serverVersionRegex = regexp.MustCompile(`^((\d+)(\.\d+)?(\.\d+)?)`)
-
-func queryVersion(db *sql.DB) (semver.Version, error) {
-	var version string
-	err := db.QueryRow("SELECT version();").Scan(&version)
-	if err != nil {
-		return semver.Version{}, err
-	}
-	submatches := versionRegex.FindStringSubmatch(version)
-	if len(submatches) > 1 {
-		return semver.ParseTolerant(submatches[1])
-	}
-
-	// We could also try to parse the version from the server_version field.
-	// This is of the format 13.3 (Debian 13.3-1.pgdg100+1)
-	err = db.QueryRow("SHOW server_version;").Scan(&version)
-	if err != nil {
-		return semver.Version{}, err
-	}
-	submatches = serverVersionRegex.FindStringSubmatch(version)
-	if len(submatches) > 1 {
-		return semver.ParseTolerant(submatches[1])
-	}
-	return semver.Version{}, fmt.Errorf("could not parse version from %q", version)
-}
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	_ "gitee.com/opengauss/openGauss-connector-go-pq"
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+)
+
+type instance struct {
+	dsn     string
+	db      *sql.DB
+	version semver.Version
+	logger  *utils.Logger
+
+	// pooled is set if db is owned by the caller rather than the instance.
+	pooled bool
+	// versionFunc, if set, is used instead of querying the version.
+	versionFunc func(context.Context) (semver.Version, error)
+	// connect, if set, returns the pooled handle setup uses as db, and the
+	// function giving it back.
+	connect func(context.Context) (*sql.DB, func(), error)
+	// release, if set, gives back the handle returned by connect.
+	release func()
+	// conn, if set, is the connection reserved for a single collector.
+	conn *sql.Conn
+	// databases picks the databases per-database metrics are collected for.
+	databases DatabaseFilter
+}
+
+func newInstance(dsn string) (*instance, error) {
+	i := &instance{
+		dsn:    dsn,
+		logger: utils.GetLogger(),
+	}
+
+	// "Create" a database handle to verify the DSN provided is valid.
+	// Open is not guaranteed to create a connection.
+	db, err := sql.Open("opengauss", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.Close()
+
+	return i, nil
+}
+
+// copy returns a copy of the instance.
+func (i *instance) copy() *instance {
+	c := &instance{
+		dsn:    i.dsn,
+		logger: i.logger,
+	}
+	if i.pooled {
+		c.db, c.pooled = i.db, true
+	}
+	c.versionFunc = i.versionFunc
+	c.connect = i.connect
+	c.databases = i.databases
+	return c
+}
+
+// withConn returns a shallow copy of the instance that queries through conn
+// and logs through logger. The copy shares the database handle of the
+// original.
+func (i *instance) withConn(conn *sql.Conn, logger *utils.Logger) *instance {
+	c := *i
+	c.conn = conn
+	c.logger = logger
+	c.release = nil
+	return &c
+}
+
+// setup opens the database handle, unless the instance is pooled, and
+// queries the server version.
+func (i *instance) setup(ctx context.Context) error {
+	if i.connect != nil {
+		db, release, err := i.connect(ctx)
+		if err != nil {
+			return err
+		}
+		i.db, i.pooled, i.release = db, true, release
+	} else if !i.pooled {
+		db, err := sql.Open("opengauss", i.dsn)
+		if err != nil {
+			return err
+		}
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		i.db = db
+	}
+
+	var version semver.Version
+	var err error
+	if i.versionFunc != nil {
+		version, err = i.versionFunc(ctx)
+	} else {
+		version, err = queryVersion(ctx, i.db)
+	}
+	if err != nil {
+		return fmt.Errorf("error querying gauss version: %w", err)
+	} else {
+		i.version = version
+	}
+	return nil
+}
+
+// queryer is the part of *sql.DB and *sql.Conn the collectors use.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (i *instance) getDB() queryer {
+	if i.conn != nil {
+		return i.conn
+	}
+	return i.db
+}
+
+// Close closes the database handle, unless it is pooled, in which case it is
+// given back if it came from connect.
+func (i *instance) Close() error {
+	if i.release != nil {
+		i.release()
+		i.release = nil
+	}
+	if i.pooled || i.db == nil {
+		return nil
+	}
+	return i.db.Close()
+}
+
+// Regex used to get the "short-version" from the postgres version field.
+// The result of SELECT version() is something like "PostgreSQL 9.6.2 on x86_64-pc-linux-gnu, compiled by gcc (GCC) 6.2.1 20160830, 64-bit"
+var versionRegex = regexp.MustCompile(`^\w+ ((\d+)(\.\d+)?(\.\d+)?)`)
+var serverVersionRegex = regexp.MustCompile(`^((\d+)(\.\d+)?(\.\d+)?)`)
+
+func queryVersion(ctx context.Context, db *sql.DB) (semver.Version, error) {
+	var version string
+	err := db.QueryRowContext(ctx, "SELECT version();").Scan(&version)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	submatches := versionRegex.FindStringSubmatch(version)
+	if len(submatches) > 1 {
+		return semver.ParseTolerant(submatches[1])
+	}
+
+	// We could also try to parse the version from the server_version field.
+	// This is of the format 13.3 (Debian 13.3-1.pgdg100+1)
+	err = db.QueryRowContext(ctx, "SHOW server_version;").Scan(&version)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	submatches = serverVersionRegex.FindStringSubmatch(version)
+	if len(submatches) > 1 {
+		return semver.ParseTolerant(submatches[1])
+	}
+	return semver.Version{}, fmt.Errorf("could not parse version from %q", version)
+}
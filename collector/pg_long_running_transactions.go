@@ -1,95 +1,94 @@
-// Copyright 2023 The Prometheus Authors
-// Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this file except in compliance with the License.
-// You may obtain a copy of the License at
-//
-// http://www.apache.org/licenses/LICENSE-2.0
-//
-// Unless required by applicable law or agreed to in writing, software
-// distributed under the License is distributed on an "AS IS" BASIS,
-// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-// See the License for the specific language governing permissions and
-// limitations under the License.
-
-package collector
-
-import (
-	"log"
-	"context"
-
-	"github.com/prometheus/client_golang/prometheus"
-)
-
-const longRunningTransactionsSubsystem = "long_running_transactions"
-
-func init() {
-	registerCollector(longRunningTransactionsSubsystem, defaultDisabled, NewPGLongRunningTransactionsCollector)
-}
-
-type PGLongRunningTransactionsCollector struct {
-}
-
-func NewPGLongRunningTransactionsCollector() (Collector, error) {
-	return &PGLongRunningTransactionsCollector{}, nil
-}
-
-var (
-	longRunningTransactionsCount = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace,longRunningTransactionsSubsystem,"total_numbers"),
-		"Current number of long running transactions",
-		[]string{},
-		prometheus.Labels{},
-	)
-
-	longRunningTransactionsAgeInSeconds = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, longRunningTransactionsSubsystem, "oldest_timestamp_seconds"),
-		"The current maximum transaction age in seconds",
-		[]string{},
-		prometheus.Labels{},
-	)
-
-	longRunningTransactionsQuery = `
-	SELECT
-		COUNT(*) as transactions,
-   		MAX(EXTRACT(EPOCH FROM clock_timestamp())) AS oldest_timestamp_seconds
-    FROM pg_catalog.pg_stat_activity
-    WHERE state is distinct from 'idle' AND query not like 'autovacuum:%'
-	`
-)
-
-func (PGLongRunningTransactionsCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
-	db := instance.getDB()
-	rows, err := db.QueryContext(ctx,
-		longRunningTransactionsQuery)
-
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var transactions, ageInSeconds float64
-
-		if err := rows.Scan(&transactions, &ageInSeconds); err != nil {
-			return err
-		}
-
-		ch <- prometheus.MustNewConstMetric(
-			longRunningTransactionsCount,
-			prometheus.GaugeValue,
-			transactions,
-		)
-
-		log.Println("Log From long running transactions",transactions)
-
-		ch <- prometheus.MustNewConstMetric(
-			longRunningTransactionsAgeInSeconds,
-			prometheus.GaugeValue,
-			ageInSeconds,
-		)
-	}
-	if err := rows.Err(); err != nil {
-		return err
-	}
-	return nil
-}
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const longRunningTransactionsSubsystem = "long_running_transactions"
+
+func init() {
+	registerCollector(longRunningTransactionsSubsystem, defaultDisabled, NewPGLongRunningTransactionsCollector)
+}
+
+type PGLongRunningTransactionsCollector struct {
+}
+
+func NewPGLongRunningTransactionsCollector() (Collector, error) {
+	return &PGLongRunningTransactionsCollector{}, nil
+}
+
+var (
+	longRunningTransactionsCount = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace,longRunningTransactionsSubsystem,"total_numbers"),
+		"Current number of long running transactions",
+		[]string{},
+		prometheus.Labels{},
+	)
+
+	longRunningTransactionsAgeInSeconds = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, longRunningTransactionsSubsystem, "oldest_timestamp_seconds"),
+		"The current maximum transaction age in seconds",
+		[]string{},
+		prometheus.Labels{},
+	)
+
+	longRunningTransactionsQuery = `
+	SELECT
+		COUNT(*) as transactions,
+   		MAX(EXTRACT(EPOCH FROM clock_timestamp())) AS oldest_timestamp_seconds
+    FROM pg_catalog.pg_stat_activity
+    WHERE state is distinct from 'idle' AND query not like 'autovacuum:%'
+	`
+)
+
+func (PGLongRunningTransactionsCollector) Update(ctx context.Context, instance *instance, ch chan<- prometheus.Metric) error {
+	db := instance.getDB()
+	rows, err := db.QueryContext(ctx,
+		longRunningTransactionsQuery)
+
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transactions, ageInSeconds float64
+
+		if err := rows.Scan(&transactions, &ageInSeconds); err != nil {
+			return err
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			longRunningTransactionsCount,
+			prometheus.GaugeValue,
+			transactions,
+		)
+
+		instance.logger.Debug("Long running transactions", "count", transactions)
+
+		ch <- prometheus.MustNewConstMetric(
+			longRunningTransactionsAgeInSeconds,
+			prometheus.GaugeValue,
+			ageInSeconds,
+		)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return nil
+}
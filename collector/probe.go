@@ -1,91 +1,189 @@
-// Copyright 2022 The Prometheus Authors
-// Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this file except in compliance with the License.
-// You may obtain a copy of the License at
-//
-// http://www.apache.org/licenses/LICENSE-2.0
-//
-// Unless required by applicable law or agreed to in writing, software
-// distributed under the License is distributed on an "AS IS" BASIS,
-// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-// See the License for the specific language governing permissions and
-// limitations under the License.
-
-package collector
-
-import (
-	"context"
-	"sync"
-
-	"github.com/prometheus-community/gaussdb_exporter/utils"
-	"github.com/prometheus/client_golang/prometheus"
-)
-
-type ProbeCollector struct {
-	registry   *prometheus.Registry
-	collectors map[string]Collector
-	instance   *instance
-}
-
-func NewProbeCollector(registry *prometheus.Registry, dsn string) (*ProbeCollector, error) {
-	collectors := make(map[string]Collector)
-	initiatedCollectorsMtx.Lock()
-	defer initiatedCollectorsMtx.Unlock()
-	for key, enabled := range collectorState {
-		// TODO: Handle filters
-		// if !*enabled || (len(f) > 0 && !f[key]) {
-		// 	continue
-		// }
-		if !*enabled {
-			continue
-		}
-		if collector, ok := initiatedCollectors[key]; ok {
-			collectors[key] = collector
-		} else {
-			collector, err := factories[key]()
-			if err != nil {
-				return nil, err
-			}
-			collectors[key] = collector
-			initiatedCollectors[key] = collector
-		}
-	}
-
-	instance, err := newInstance(dsn)
-	if err != nil {
-		return nil, err
-	}
-
-	return &ProbeCollector{
-		registry:   registry,
-		collectors: collectors,
-		instance:   instance,
-	}, nil
-}
-
-func (pc *ProbeCollector) Describe(ch chan<- *prometheus.Desc) {
-}
-
-func (pc *ProbeCollector) Collect(ch chan<- prometheus.Metric) {
-	// Set up the database connection for the collector.
-	err := pc.instance.setup()
-	if err != nil {
-		utils.GetLogger().Error("Error opening connection to database", "err", err)
-		return
-	}
-	defer pc.instance.Close()
-
-	wg := sync.WaitGroup{}
-	wg.Add(len(pc.collectors))
-	for name, c := range pc.collectors {
-		go func(name string, c Collector) {
-			execute(context.TODO(), name, c, pc.instance, ch)
-			wg.Done()
-		}(name, c)
-	}
-	wg.Wait()
-}
-
-func (pc *ProbeCollector) Close() error {
-	return pc.instance.Close()
-}
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ProbeCollector struct {
+	registry   *prometheus.Registry
+	collectors map[string]Collector
+	instance   *instance
+
+	ctx       context.Context
+	db        *sql.DB
+	version   func(context.Context) (semver.Version, error)
+	logger    *utils.Logger
+	collect   []string
+	exclude   []string
+	overrides Overrides
+	databases DatabaseFilter
+	timeout   time.Duration
+	timeouts  map[string]time.Duration
+}
+
+// ProbeOpt configures a ProbeCollector.
+type ProbeOpt func(*ProbeCollector)
+
+// ProbeWithContext sets the context the collectors run their queries under.
+// When it is done, running queries are cancelled and the probe returns.
+func ProbeWithContext(ctx context.Context) ProbeOpt {
+	return func(pc *ProbeCollector) {
+		pc.ctx = ctx
+	}
+}
+
+// ProbeWithLogger sets the logger the collectors of the probe log through.
+func ProbeWithLogger(logger *utils.Logger) ProbeOpt {
+	return func(pc *ProbeCollector) {
+		pc.logger = logger
+	}
+}
+
+// ProbeWithTimeouts overrides the --collector.timeout flag with timeout, if
+// positive, and with timeouts for the named collectors.
+func ProbeWithTimeouts(timeout time.Duration, timeouts map[string]time.Duration) ProbeOpt {
+	return func(pc *ProbeCollector) {
+		if timeout > 0 {
+			pc.timeout = timeout
+		}
+		pc.timeouts = timeouts
+	}
+}
+
+// ProbeWithDB makes the probe query through db, a pooled handle the probe
+// doesn't close, instead of connecting on every scrape.
+func ProbeWithDB(db *sql.DB) ProbeOpt {
+	return func(pc *ProbeCollector) {
+		pc.db = db
+	}
+}
+
+// ProbeWithVersion makes the probe get the server version from version,
+// which may cache it, instead of querying it on every scrape.
+func ProbeWithVersion(version func(context.Context) (semver.Version, error)) ProbeOpt {
+	return func(pc *ProbeCollector) {
+		pc.version = version
+	}
+}
+
+// ProbeCollect limits the probe to the named collectors.
+func ProbeCollect(names []string) ProbeOpt {
+	return func(pc *ProbeCollector) {
+		pc.collect = names
+	}
+}
+
+// ProbeExclude skips the named collectors.
+func ProbeExclude(names []string) ProbeOpt {
+	return func(pc *ProbeCollector) {
+		pc.exclude = names
+	}
+}
+
+// ProbeWithOverrides turns collectors on or off for the probed instance.
+// ProbeCollect can still pick collectors turned off this way.
+func ProbeWithOverrides(o Overrides) ProbeOpt {
+	return func(pc *ProbeCollector) {
+		pc.overrides = o
+	}
+}
+
+// ProbeWithDatabases limits the per-database metrics of the probed instance
+// to the databases f covers.
+func ProbeWithDatabases(f DatabaseFilter) ProbeOpt {
+	return func(pc *ProbeCollector) {
+		pc.databases = f
+	}
+}
+
+// NewProbeCollector creates a ProbeCollector for dsn. It fails with an error
+// wrapping ErrUnknownCollector or ErrDisabledCollector if the filters name a
+// collector that can't be run.
+func NewProbeCollector(registry *prometheus.Registry, dsn string, opts ...ProbeOpt) (*ProbeCollector, error) {
+	pc := &ProbeCollector{
+		registry: registry,
+		ctx:      context.Background(),
+		logger:   utils.GetLogger(),
+		timeout:  *collectorTimeout,
+	}
+	for _, opt := range opts {
+		opt(pc)
+	}
+
+	collectors, err := selectCollectors(pc.collect, pc.exclude, pc.overrides)
+	if err != nil {
+		return nil, err
+	}
+	pc.collectors = collectors
+
+	instance, err := newInstance(dsn)
+	if err != nil {
+		return nil, err
+	}
+	instance.logger = pc.logger
+	instance.databases = pc.databases
+	if pc.db != nil {
+		instance.db, instance.pooled = pc.db, true
+	}
+	if pc.version != nil {
+		instance.versionFunc = pc.version
+	}
+	pc.instance = instance
+
+	return pc, nil
+}
+
+func (pc *ProbeCollector) Describe(ch chan<- *prometheus.Desc) {
+}
+
+func (pc *ProbeCollector) Collect(ch chan<- prometheus.Metric) {
+	// Set up the database connection for the collector.
+	err := pc.instance.setup(pc.ctx)
+	if err != nil {
+		pc.instance.logger.Error("Error opening connection to database", "err", err)
+		return
+	}
+	defer pc.instance.Close()
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(pc.collectors))
+	for name, c := range pc.collectors {
+		go func(name string, c Collector) {
+			execute(pc.ctx, name, c, pc.instance, pc.timeoutFor(name), ch)
+			wg.Done()
+		}(name, c)
+	}
+	wg.Wait()
+}
+
+// timeoutFor returns the timeout of the named collector.
+func (pc *ProbeCollector) timeoutFor(name string) time.Duration {
+	if timeout, ok := pc.timeouts[name]; ok {
+		return timeout
+	}
+	return pc.timeout
+}
+
+func (pc *ProbeCollector) Close() error {
+	return pc.instance.Close()
+}
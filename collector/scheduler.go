@@ -0,0 +1,287 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultInterval is how often the scheduler runs a job by default.
+const DefaultInterval = time.Minute
+
+var (
+	snapshotAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "snapshot_age_seconds"),
+		"gaussdb_exporter: Time since the served results of a background collector were collected.",
+		[]string{"collector"},
+		nil,
+	)
+	snapshotTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "snapshot_timestamp_seconds"),
+		"gaussdb_exporter: When the served results of a background collector were collected.",
+		[]string{"collector"},
+		nil,
+	)
+)
+
+// Scheduler runs the collectors of a PostgresCollector in the background,
+// each on its own interval, and serves the results of their last completed
+// run. It implements prometheus.Collector.
+type Scheduler struct {
+	jobs      []job
+	hidden    map[string]bool
+	interval  time.Duration
+	intervals map[string]time.Duration
+	timeout   time.Duration
+	timeouts  map[string]time.Duration
+
+	mtx       sync.RWMutex
+	snapshots map[string]snapshot
+}
+
+type job struct {
+	name string
+	run  func(ctx context.Context, ch chan<- prometheus.Metric)
+}
+
+// snapshot is the result of a job run.
+type snapshot struct {
+	metrics []prometheus.Metric
+	at      time.Time
+}
+
+// SchedulerOpt configures a Scheduler.
+type SchedulerOpt func(*Scheduler)
+
+// SchedulerWithIntervals runs every job each interval, if positive, and the
+// named jobs each of intervals.
+func SchedulerWithIntervals(interval time.Duration, intervals map[string]time.Duration) SchedulerOpt {
+	return func(s *Scheduler) {
+		if interval > 0 {
+			s.interval = interval
+		}
+		s.intervals = intervals
+	}
+}
+
+// SchedulerWithTimeouts overrides the --collector.timeout flag like
+// ProbeWithTimeouts does.
+func SchedulerWithTimeouts(timeout time.Duration, timeouts map[string]time.Duration) SchedulerOpt {
+	return func(s *Scheduler) {
+		if timeout > 0 {
+			s.timeout = timeout
+		}
+		s.timeouts = timeouts
+	}
+}
+
+// NewScheduler creates a Scheduler for the collectors of p.
+func NewScheduler(p *PostgresCollector, opts ...SchedulerOpt) *Scheduler {
+	s := &Scheduler{
+		interval:  DefaultInterval,
+		timeout:   *collectorTimeout,
+		hidden:    make(map[string]bool),
+		snapshots: make(map[string]snapshot),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	for name, c := range p.Collectors {
+		s.addCollector(p, name, c)
+	}
+	// Collectors disabled for the instance still run, so that a probe
+	// naming them in collect[] gets their results like it would without
+	// background scraping, but are left out otherwise.
+	for _, name := range p.overrides.Disable {
+		collectors, err := selectCollectors([]string{name}, nil, Overrides{Enable: p.overrides.Enable})
+		if err != nil {
+			// Disabled on the command line as well.
+			continue
+		}
+		for name, c := range collectors {
+			s.addCollector(p, name, c)
+			s.hidden[name] = true
+		}
+	}
+	return s
+}
+
+// addCollector adds a job running the collector c of p.
+func (s *Scheduler) addCollector(p *PostgresCollector, name string, c Collector) {
+	timeout := s.timeout
+	if t, ok := s.timeouts[name]; ok {
+		timeout = t
+	}
+	s.AddJob(name, func(ctx context.Context, ch chan<- prometheus.Metric) {
+		inst := p.instance.copy()
+		if err := inst.setup(ctx); err != nil {
+			inst.logger.Error("Error opening connection to database", "collector", name, "err", err)
+			ch <- prometheus.MustNewConstMetric(scrapeSuccessDesc, prometheus.GaugeValue, 0, name)
+			return
+		}
+		defer inst.Close()
+		execute(ctx, name, c, inst, timeout, ch)
+	})
+}
+
+// AddJob adds a job that isn't one of the collectors, such as the exporter's
+// own queries. Jobs must be added before Run.
+func (s *Scheduler) AddJob(name string, run func(ctx context.Context, ch chan<- prometheus.Metric)) {
+	s.jobs = append(s.jobs, job{name: name, run: run})
+}
+
+// Run runs every job right away and then on its interval until ctx is done.
+// A run is cut short when it takes longer than the interval.
+func (s *Scheduler) Run(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	wg.Add(len(s.jobs))
+	for _, j := range s.jobs {
+		go func(j job) {
+			defer wg.Done()
+			interval := s.interval
+			if i, ok := s.intervals[j.name]; ok {
+				interval = i
+			}
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				s.runJob(ctx, j, interval)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(j)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) runJob(ctx context.Context, j job, interval time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	var metrics []prometheus.Metric
+	go func() {
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		close(done)
+	}()
+	j.run(ctx, ch)
+	close(ch)
+	<-done
+
+	// A run cancelled by Run returning is not a result worth keeping.
+	if ctx.Err() == context.Canceled {
+		return
+	}
+
+	s.mtx.Lock()
+	s.snapshots[j.name] = snapshot{metrics: metrics, at: time.Now()}
+	s.mtx.Unlock()
+}
+
+// Describe implements prometheus.Collector. The scheduler is an unchecked
+// collector, since its jobs don't describe their metrics up front.
+func (s *Scheduler) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect implements prometheus.Collector.
+func (s *Scheduler) Collect(ch chan<- prometheus.Metric) {
+	s.collect(ch, nil)
+}
+
+// Filter returns a view of the scheduler limited like ProbeCollect and
+// ProbeExclude limit a probe: collectors disabled for the instance are only
+// served when named in collect. Naming a job that doesn't exist is an error
+// wrapping ErrUnknownCollector, or ErrDisabledCollector if it is a collector
+// disabled on the command line.
+func (s *Scheduler) Filter(collect, exclude []string) (prometheus.Collector, error) {
+	if len(collect) == 0 && len(exclude) == 0 {
+		return s, nil
+	}
+
+	jobs := make(map[string]bool, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs[j.name] = true
+	}
+	for _, filter := range [][]string{collect, exclude} {
+		for _, name := range filter {
+			if jobs[name] {
+				continue
+			}
+			if _, exist := collectorState[name]; exist {
+				return nil, fmt.Errorf("%w: %s", ErrDisabledCollector, name)
+			}
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCollector, name)
+		}
+	}
+
+	names := make(map[string]bool)
+	if len(collect) > 0 {
+		for _, name := range collect {
+			names[name] = true
+		}
+	} else {
+		for name := range jobs {
+			if !s.hidden[name] {
+				names[name] = true
+			}
+		}
+	}
+	for _, name := range exclude {
+		delete(names, name)
+	}
+	return schedulerView{s: s, jobs: names}, nil
+}
+
+// collect sends the snapshots of the jobs in names, or of all jobs but the
+// hidden ones if names is nil, with the time they were taken.
+func (s *Scheduler) collect(ch chan<- prometheus.Metric, names map[string]bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	for name, snap := range s.snapshots {
+		if (names == nil && s.hidden[name]) || (names != nil && !names[name]) {
+			continue
+		}
+		for _, m := range snap.metrics {
+			ch <- prometheus.NewMetricWithTimestamp(snap.at, m)
+		}
+		ch <- prometheus.MustNewConstMetric(snapshotAgeDesc, prometheus.GaugeValue, time.Since(snap.at).Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(snapshotTimestampDesc, prometheus.GaugeValue, float64(snap.at.UnixNano())/1e9, name)
+	}
+}
+
+type schedulerView struct {
+	s    *Scheduler
+	jobs map[string]bool
+}
+
+func (v schedulerView) Describe(ch chan<- *prometheus.Desc) {
+}
+
+func (v schedulerView) Collect(ch chan<- prometheus.Metric) {
+	v.s.collect(ch, v.jobs)
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestScheduler(t *testing.T) {
+	desc := prometheus.NewDesc("test_metric", "Test metric.", nil, nil)
+	s := NewScheduler(&PostgresCollector{}, SchedulerWithIntervals(time.Hour, nil))
+	s.AddJob("test", func(ctx context.Context, ch chan<- prometheus.Metric) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	for i := 0; testutil.CollectAndCount(s) == 0; i++ {
+		if i == 100 {
+			t.Fatal("no snapshot after 1s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// The metric of the job, its age and its timestamp.
+	if n := testutil.CollectAndCount(s); n != 3 {
+		t.Errorf("expected 3 metrics, got %d", n)
+	}
+	if n := testutil.CollectAndCount(s, "test_metric"); n != 1 {
+		t.Errorf("expected test_metric once, got %d", n)
+	}
+
+	view, err := s.Filter(nil, []string{"test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(view); n != 0 {
+		t.Errorf("expected no metrics with the job excluded, got %d", n)
+	}
+	if _, err := s.Filter([]string{"nope"}, nil); !errors.Is(err, ErrUnknownCollector) {
+		t.Errorf("expected ErrUnknownCollector, got %v", err)
+	}
+}
+
+func TestSchedulerDisabled(t *testing.T) {
+	desc := prometheus.NewDesc("test_metric", "Test metric.", nil, nil)
+	p := &PostgresCollector{overrides: Overrides{Disable: []string{"locks"}}}
+	s := NewScheduler(p)
+	if !s.hidden["locks"] {
+		t.Fatal("expected a hidden job for the locks collector disabled for the instance")
+	}
+	s.AddJob("test", nil)
+	m := prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1)
+	s.snapshots["locks"] = snapshot{metrics: []prometheus.Metric{m}, at: time.Now()}
+	s.snapshots["test"] = snapshot{metrics: []prometheus.Metric{m}, at: time.Now()}
+
+	if n := testutil.CollectAndCount(s, "test_metric"); n != 1 {
+		t.Errorf("expected test_metric once without the disabled collector, got %d", n)
+	}
+	view, err := s.Filter(nil, []string{"test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(view); n != 0 {
+		t.Errorf("expected no metrics with only the disabled collector left, got %d", n)
+	}
+	view, err = s.Filter([]string{"locks"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(view, "test_metric"); n != 1 {
+		t.Errorf("expected test_metric once with the disabled collector named, got %d", n)
+	}
+	if _, err := s.Filter([]string{"stat_user_tables"}, nil); !errors.Is(err, ErrDisabledCollector) {
+		t.Errorf("expected ErrDisabledCollector, got %v", err)
+	}
+}
@@ -1,141 +1,432 @@
-// Copyright 2022 The Prometheus Authors
-// Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this file except in compliance with the License.
-// You may obtain a copy of the License at
-//
-// http://www.apache.org/licenses/LICENSE-2.0
-//
-// Unless required by applicable law or agreed to in writing, software
-// distributed under the License is distributed on an "AS IS" BASIS,
-// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-// See the License for the specific language governing permissions and
-// limitations under the License.
-
-package config
-
-import (
-	"fmt"
-	"os"
-	"sync"
-	"io/ioutil"
-
-	"gopkg.in/yaml.v3"
-)
-
-var (
-	configName = "config.yml"
-)
-
-type Config struct {
-	Log       Log        `yaml:"log"`
-	Instances []Instance `yaml:"instances"`
-}
-
-type Log struct {
-	Level  string `yaml:"level"`
-	MaxAge int    `yaml:"max_age"`
-}
-
-type Instance struct {
-	InstanceId string `yaml:"instance_id"`
-	ExcludeDbs string `yaml:"exclude_dbs"`
-	Host       string `yaml:"host"`
-	Port       string `yaml:"port"`
-	Db         string `yaml:"db"`
-	User       string `yaml:"user"`
-	Password   string `yaml:"password"`
-}
-
-type Handler struct {
-	sync.RWMutex
-	Config *Config
-}
-
-func (ch *Handler) GetConfig() *Config {
-	ch.RLock()
-	defer ch.RUnlock()
-	return ch.Config
-}
-
-func (ch *Handler) SetConfig(c *Config) {
-	ch.RLock()
-	defer ch.RUnlock()
-	ch.Config = c
-}
-
-func (ch *Handler) ReloadConfig() error {
-	config := &Config{}
-
-	yamlReader, err := os.Open(configName)
-	if err != nil {
-		return fmt.Errorf("Error opening config file %q: %s", configName, err)
-	}
-	defer yamlReader.Close()
-	decoder := yaml.NewDecoder(yamlReader)
-	decoder.KnownFields(true)
-
-	if err = decoder.Decode(config); err != nil {
-		return fmt.Errorf("Error parsing config file %q: %s", configName, err)
-	}
-
-	ch.SetConfig(config)
-	return nil
-}
-
-func (ch *Handler) WriteConfigFile(tgi Config) int {
-	data,err := yaml.Marshal(tgi)
-	if err != nil {
-		panic(err)
-		return -1
-	}
-
-	err = ioutil.WriteFile(configName,data,0777)
-	if err != nil {
-		panic(err)
-		return -1
-	}
-	return 0
-}
-
-func (ch *Handler) InitConfig(instances []Instance,log Log) Config {
-	config := Config {
-		Log: log,
-		Instances: instances,
-	}
-
-	return config
-}
-
-func (ch *Handler) InitLog(level string,maxage int) Log {
-	log := Log {
-		Level: level,
-		MaxAge: maxage,
-	}
-
-	return log
-}
-
-func (ch *Handler) InitInstance(instance_id string, excludedbs string, hosts string, port string, db string, user string, passwd string) Instance {
-	instance := Instance {
-		InstanceId: instance_id,
-		ExcludeDbs: excludedbs,
-		Host: hosts,
-		Port: port,
-		Db: db,
-		User: user,
-		Password: passwd,
-	}
-
-	return instance
-}
-
-func (ch *Handler) InitFromUrl(instance_id string, excludedbs string, hosts string, port string, db string, user string, passwd string,log_level string,log_max_age int) Config {
-
-	log := ch.InitLog(log_level,log_max_age)
-	instance := ch.InitInstance(instance_id,excludedbs,hosts,port,db,user,passwd)
-	instances := []Instance{instance}
-
-	config := ch.InitConfig(instances,log)
-
-	return config
-}
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is the config file read when Handler.File is empty.
+const DefaultFile = "config.yml"
+
+// EncryptedPrefix marks a password in config.yml as ciphertext that has to be
+// decrypted with Handler.Decrypt before use.
+const EncryptedPrefix = "enc:"
+
+type Config struct {
+	Log        Log        `yaml:"log"`
+	Collector  Collector  `yaml:"collector,omitempty"`
+	Pool       Pool       `yaml:"pool,omitempty"`
+	Background Background `yaml:"background,omitempty"`
+	Instances  []Instance `yaml:"instances"`
+
+	// sources names the file each instance was loaded from, when the
+	// config comes from more than one.
+	sources []string
+}
+
+type Log struct {
+	Level  string `yaml:"level"`
+	MaxAge int    `yaml:"max_age"` // days
+
+	// Optional, the command line values are used when these are empty.
+	Format       string        `yaml:"format,omitempty"`
+	Dir          string        `yaml:"dir,omitempty"`
+	RotationTime time.Duration `yaml:"rotation_time,omitempty"`
+}
+
+// Collector configures the collectors of the collector package.
+type Collector struct {
+	// Timeout limits how long each collector may run. When zero the
+	// --collector.timeout flag applies.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Timeouts overrides Timeout for the named collectors.
+	Timeouts map[string]time.Duration `yaml:"timeouts,omitempty"`
+}
+
+// Background configures background scraping, where every instance is
+// scraped on a schedule and /metrics serves the results of the last run.
+type Background struct {
+	Enabled bool `yaml:"enabled"`
+	// Interval is how often the collectors run, one minute if zero.
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Intervals overrides Interval for the named collectors.
+	Intervals map[string]time.Duration `yaml:"intervals,omitempty"`
+}
+
+// Pool configures the connections kept open to every instance between
+// scrapes. Zero values leave the matching --db.* flag in effect.
+type Pool struct {
+	MaxOpenConns int           `yaml:"max_open_conns,omitempty"`
+	MaxIdleConns int           `yaml:"max_idle_conns,omitempty"`
+	MaxIdleTime  time.Duration `yaml:"max_idle_time,omitempty"`
+}
+
+type Instance struct {
+	InstanceId string `yaml:"instance_id"`
+	// ExcludeDbs and IncludeDbs are comma separated database names. The
+	// per-database metrics always leave out the excluded databases. If any
+	// databases are included they cover only those, otherwise all but
+	// template0, template1 and security.
+	ExcludeDbs string `yaml:"exclude_dbs"`
+	IncludeDbs string `yaml:"include_dbs,omitempty"`
+	// Host may list several comma separated hosts, tried in turn, in which
+	// case Port is either one port for all of them or one port per host.
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Db       string `yaml:"db"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+
+	// Optional connection settings, passed on to the driver. SSLMode is
+	// "disable" if empty.
+	SSLMode            string        `yaml:"sslmode,omitempty"`
+	SSLRootCert        string        `yaml:"sslrootcert,omitempty"`
+	SSLCert            string        `yaml:"sslcert,omitempty"`
+	SSLKey             string        `yaml:"sslkey,omitempty"`
+	ConnectTimeout     time.Duration `yaml:"connect_timeout,omitempty"`
+	ApplicationName    string        `yaml:"application_name,omitempty"`
+	TargetSessionAttrs string        `yaml:"target_session_attrs,omitempty"`
+
+	// Alternatives to Password, resolved by ResolvePassword on every
+	// connection attempt. At most one password source may be set.
+	PasswordFile    string `yaml:"password_file,omitempty"`
+	PasswordEnv     string `yaml:"password_env,omitempty"`
+	PasswordCommand string `yaml:"password_command,omitempty"`
+
+	// Labels are added, along with instance_id, to every metric scraped
+	// from the instance that doesn't have a label of the same name.
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// Collectors turns collectors on or off for this instance.
+	Collectors Collectors `yaml:"collectors,omitempty"`
+}
+
+// ExcludedDatabases returns the databases listed in ExcludeDbs.
+func (i Instance) ExcludedDatabases() []string {
+	return splitDbs(i.ExcludeDbs)
+}
+
+// IncludedDatabases returns the databases listed in IncludeDbs.
+func (i Instance) IncludedDatabases() []string {
+	return splitDbs(i.IncludeDbs)
+}
+
+// splitDbs splits a comma separated list of database names, ignoring
+// surrounding spaces.
+func splitDbs(s string) []string {
+	var dbs []string
+	for _, db := range strings.Split(s, ",") {
+		if db = strings.TrimSpace(db); db != "" {
+			dbs = append(dbs, db)
+		}
+	}
+	return dbs
+}
+
+// Collectors overrides the --collector.<name> flags for one instance. A
+// probe's collect[] parameter can still pick disabled collectors.
+type Collectors struct {
+	Enable  []string `yaml:"enable,omitempty"`
+	Disable []string `yaml:"disable,omitempty"`
+}
+
+type Handler struct {
+	sync.RWMutex
+	Config *Config
+
+	// File is the config file, DefaultFile if empty.
+	File string
+	// Dir, if set, is a directory whose *.yml files each add instances to
+	// those of File, which may then be missing. Instances are numbered
+	// across the files in validation errors, File first and the rest in
+	// lexical order.
+	Dir string
+
+	// Decrypt turns an encrypted password, without its EncryptedPrefix,
+	// into plaintext. Encrypted passwords are rejected if it is nil.
+	Decrypt func(ciphertext string) (string, error)
+
+	// KnownCollectors lists the collector names the config may refer to.
+	// Names are not checked if it is empty.
+	KnownCollectors []string
+}
+
+func (ch *Handler) GetConfig() *Config {
+	ch.RLock()
+	defer ch.RUnlock()
+	return ch.Config
+}
+
+func (ch *Handler) SetConfig(c *Config) {
+	ch.Lock()
+	defer ch.Unlock()
+	ch.Config = c
+}
+
+// ReloadConfig reads and validates the config file and swaps it in. The
+// current config is left untouched if the new one can't be loaded.
+func (ch *Handler) ReloadConfig() error {
+	config, err := ch.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	ch.SetConfig(config)
+	return nil
+}
+
+// LoadConfig reads and validates the config files without making them
+// active.
+func (ch *Handler) LoadConfig() (*Config, error) {
+	config := &Config{}
+	name := ch.file()
+	if _, err := os.Stat(name); ch.Dir == "" || err == nil {
+		if err := decodeFile(name, config, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if ch.Dir != "" {
+		files, err := ch.dirFiles()
+		if err != nil {
+			return nil, err
+		}
+		// Validation points out instances defined in more than one file
+		// by the files they came from.
+		for range config.Instances {
+			config.sources = append(config.sources, name)
+		}
+		for _, file := range files {
+			part := struct {
+				Instances []Instance `yaml:"instances"`
+			}{}
+			if err := decodeFile(file, &part, true); err != nil {
+				return nil, err
+			}
+			for range part.Instances {
+				config.sources = append(config.sources, file)
+			}
+			config.Instances = append(config.Instances, part.Instances...)
+		}
+		name = fmt.Sprintf("%s and %s", name, filepath.Join(ch.Dir, "*.yml"))
+	}
+
+	if err := config.expandEnv(); err != nil {
+		return nil, fmt.Errorf("Error expanding environment variables in config %q: %w", name, err)
+	}
+
+	if err := config.validate(ch.KnownCollectors); err != nil {
+		return nil, fmt.Errorf("Error validating config %q: %w", name, err)
+	}
+
+	if err := ch.decryptPasswords(config); err != nil {
+		return nil, fmt.Errorf("Error decrypting config %q: %s", name, err)
+	}
+
+	return config, nil
+}
+
+func (ch *Handler) file() string {
+	if ch.File == "" {
+		return DefaultFile
+	}
+	return ch.File
+}
+
+// dirFiles returns the *.yml files in Dir, in lexical order, leaving out
+// File should it be one of them.
+func (ch *Handler) dirFiles() ([]string, error) {
+	entries, err := os.ReadDir(ch.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading config directory %q: %s", ch.Dir, err)
+	}
+	mainFile, _ := os.Stat(ch.file())
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yml" {
+			continue
+		}
+		file := filepath.Join(ch.Dir, entry.Name())
+		if fi, err := os.Stat(file); err == nil && mainFile != nil && os.SameFile(fi, mainFile) {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// decodeFile decodes the YAML file name into out, rejecting unknown fields.
+// An empty file is an error unless allowEmpty is set.
+func decodeFile(name string, out interface{}, allowEmpty bool) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("Error opening config file %q: %s", name, err)
+	}
+	defer f.Close()
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(out); err != nil && !(allowEmpty && err == io.EOF) {
+		return fmt.Errorf("Error parsing config file %q: %s", name, err)
+	}
+	return nil
+}
+
+// decryptPasswords replaces every encrypted instance password in config with
+// its plaintext.
+func (ch *Handler) decryptPasswords(config *Config) error {
+	for i := range config.Instances {
+		instance := &config.Instances[i]
+		if !strings.HasPrefix(instance.Password, EncryptedPrefix) {
+			continue
+		}
+		if ch.Decrypt == nil {
+			return fmt.Errorf("instances[%d].password: encrypted password but no decryption configured", i)
+		}
+		password, err := ch.Decrypt(strings.TrimPrefix(instance.Password, EncryptedPrefix))
+		if err != nil {
+			return fmt.Errorf("instances[%d].password: %s", i, err)
+		}
+		instance.Password = password
+	}
+	return nil
+}
+
+// Watch polls the config files every interval and calls onChange whenever
+// one of them is added, removed or differs in modification time or size
+// from what was last seen. It never returns.
+func (ch *Handler) Watch(interval time.Duration, onChange func()) {
+	last := ch.fingerprint()
+	for range time.Tick(interval) {
+		current := ch.fingerprint()
+		if current == last {
+			continue
+		}
+		last = current
+		onChange()
+	}
+}
+
+// fingerprint sums up the name, modification time and size of every config
+// file.
+func (ch *Handler) fingerprint() string {
+	files := []string{ch.file()}
+	if ch.Dir != "" {
+		// A missing directory is reported by LoadConfig.
+		dirFiles, _ := ch.dirFiles()
+		files = append(files, dirFiles...)
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		if fi, err := os.Stat(file); err == nil {
+			fmt.Fprintf(&b, "%s %d %d\n", file, fi.ModTime().UnixNano(), fi.Size())
+		}
+	}
+	return b.String()
+}
+
+// WriteConfigFile writes tgi to File. The file is replaced atomically and
+// only readable by the exporter's user, as it holds passwords.
+func (ch *Handler) WriteConfigFile(tgi Config) int {
+	data,err := yaml.Marshal(tgi)
+	if err != nil {
+		panic(err)
+	}
+
+	err = writeFileAtomic(ch.file(), data, 0600)
+	if err != nil {
+		panic(err)
+	}
+	return 0
+}
+
+// writeFileAtomic writes data to a temporary file next to name and renames
+// it over name, so that readers see either the old or the new content.
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+func (ch *Handler) InitConfig(instances []Instance,log Log) Config {
+	config := Config {
+		Log: log,
+		Instances: instances,
+	}
+
+	return config
+}
+
+func (ch *Handler) InitLog(level string,maxage int) Log {
+	log := Log {
+		Level: level,
+		MaxAge: maxage,
+	}
+
+	return log
+}
+
+func (ch *Handler) InitInstance(instance_id string, excludedbs string, hosts string, port string, db string, user string, passwd string) Instance {
+	instance := Instance {
+		InstanceId: instance_id,
+		ExcludeDbs: excludedbs,
+		Host: hosts,
+		Port: port,
+		Db: db,
+		User: user,
+		Password: passwd,
+	}
+
+	return instance
+}
+
+func (ch *Handler) InitFromUrl(instance_id string, excludedbs string, hosts string, port string, db string, user string, passwd string,log_level string,log_max_age int) Config {
+
+	log := ch.InitLog(log_level,log_max_age)
+	instance := ch.InitInstance(instance_id,excludedbs,hosts,port,db,user,passwd)
+	instances := []Instance{instance}
+
+	config := ch.InitConfig(instances,log)
+
+	return config
+}
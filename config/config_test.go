@@ -0,0 +1,349 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validInstance(id string) Instance {
+	return Instance{
+		InstanceId: id,
+		ExcludeDbs: "template0,template1",
+		Host:       "127.0.0.1",
+		Port:       "5432",
+		Db:         "postgres",
+		User:       "monitor",
+		Password:   "secret",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := &Config{
+		Log:       Log{Level: "WARN", MaxAge: 2},
+		Instances: []Instance{validInstance("a"), validInstance("b")},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %s", err)
+	}
+
+	bad := validInstance("a")
+	bad.Port = "54x2"
+	bad.User = ""
+	bad.Db = ""
+	bad.ExcludeDbs = "template0,,template1"
+	bad.IncludeDbs = "school, template1"
+	bad.Labels = map[string]string{"env": "prod", "instance_id": "x", "0zone": "a"}
+	c.Log.Level = "verbose"
+	c.Instances = append(c.Instances, bad)
+
+	err := c.Validate()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	want := []ValidationError{
+		{Index: -1, Field: "log.level"},
+		{Index: 2, Field: "instance_id"},
+		{Index: 2, Field: "port"},
+		{Index: 2, Field: "db"},
+		{Index: 2, Field: "user"},
+		{Index: 2, Field: "exclude_dbs"},
+		{Index: 2, Field: "include_dbs"},
+		{Index: 2, Field: "labels"},
+		{Index: 2, Field: "labels"},
+	}
+	if len(verrs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %s", len(want), len(verrs), err)
+	}
+	for i, w := range want {
+		if verrs[i].Index != w.Index || verrs[i].Field != w.Field {
+			t.Errorf("error %d: expected %s at index %d, got %q", i, w.Field, w.Index, verrs[i])
+		}
+	}
+}
+
+func TestValidateCollectorTimeouts(t *testing.T) {
+	c := &Config{
+		Collector: Collector{
+			Timeout: 5 * time.Second,
+			Timeouts: map[string]time.Duration{
+				"database":         10 * time.Second,
+				"stat_user_tables": 0,
+				"nope":             time.Second,
+			},
+		},
+		Instances: []Instance{validInstance("a")},
+	}
+
+	err := c.validate([]string{"database", "stat_user_tables"})
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", err)
+	}
+	if verrs[0].Msg != `unknown collector "nope"` {
+		t.Errorf("unexpected error %q", verrs[0])
+	}
+
+	c.Instances[0].Collectors = Collectors{Enable: []string{"database", "nope"}, Disable: []string{"database"}}
+	err = c.validate([]string{"database", "stat_user_tables"})
+	if !errors.As(err, &verrs) || len(verrs) != 4 {
+		t.Fatalf("expected 4 errors, got %v", err)
+	}
+	for _, verr := range verrs[2:] {
+		if verr.Index != 0 || verr.Field != "collectors.enable" {
+			t.Errorf("expected an error for collectors.enable, got %q", verr)
+		}
+	}
+	c.Instances[0].Collectors = Collectors{}
+
+	// Names can't be checked without the list of collectors.
+	delete(c.Collector.Timeouts, "stat_user_tables")
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected valid config, got %s", err)
+	}
+}
+
+func TestValidateConnectionOptions(t *testing.T) {
+	ok := validInstance("a")
+	ok.Host = "db1,db2"
+	ok.Port = "5432,5433"
+	ok.SSLMode = "verify-full"
+	ok.SSLCert, ok.SSLKey = "client.crt", "client.key"
+	ok.TargetSessionAttrs = "read-write"
+	c := &Config{Instances: []Instance{ok}}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %s", err)
+	}
+
+	bad := validInstance("b")
+	bad.Host = "db1,,db3"
+	bad.Port = "5432,5433"
+	bad.SSLMode = "on"
+	bad.SSLCert = "client.crt"
+	bad.ConnectTimeout = -time.Second
+	bad.TargetSessionAttrs = "read-only"
+	c.Instances = append(c.Instances, bad)
+
+	err := c.Validate()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	want := []string{"host", "port", "sslmode", "sslcert", "connect_timeout", "target_session_attrs"}
+	if len(verrs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %s", len(want), len(verrs), err)
+	}
+	for i, field := range want {
+		if verrs[i].Index != 1 || verrs[i].Field != field {
+			t.Errorf("error %d: expected %s at index 1, got %q", i, field, verrs[i])
+		}
+	}
+}
+
+func TestDecryptPasswords(t *testing.T) {
+	c := &Config{Instances: []Instance{validInstance("plain"), validInstance("encrypted")}}
+	c.Instances[1].Password = EncryptedPrefix + "terces"
+
+	ch := &Handler{}
+	if err := ch.decryptPasswords(c); err == nil {
+		t.Fatal("expected an error without a Decrypt func")
+	}
+
+	ch.Decrypt = func(ciphertext string) (string, error) {
+		if ciphertext != "terces" {
+			return "", errors.New("wrong key")
+		}
+		return "secret", nil
+	}
+	if err := ch.decryptPasswords(c); err != nil {
+		t.Fatal(err)
+	}
+	for _, instance := range c.Instances {
+		if instance.Password != "secret" {
+			t.Errorf("%s: expected password %q, got %q", instance.InstanceId, "secret", instance.Password)
+		}
+	}
+}
+
+func TestResolvePassword(t *testing.T) {
+	dir := t.TempDir()
+	file := dir + "/password"
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GS_TEST_PASSWORD", "from-env")
+
+	for _, tc := range []struct {
+		instance Instance
+		want     string
+	}{
+		{Instance{Password: "inline"}, "inline"},
+		{Instance{PasswordFile: file}, "from-file"},
+		{Instance{PasswordEnv: "GS_TEST_PASSWORD"}, "from-env"},
+		{Instance{PasswordCommand: "echo from-command"}, "from-command"},
+	} {
+		got, err := tc.instance.ResolvePassword()
+		if err != nil {
+			t.Errorf("%+v: %s", tc.instance, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("expected %q, got %q", tc.want, got)
+		}
+	}
+
+	if _, err := (&Instance{PasswordEnv: "GS_TEST_PASSWORD_UNSET"}).ResolvePassword(); err == nil {
+		t.Error("expected an error for an unset password_env")
+	}
+}
+
+func TestLoadConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	instance := func(id string) string {
+		return "  - instance_id: " + id + "\n    host: 127.0.0.1\n    port: 5432\n    db: postgres\n    user: monitor\n    password: secret\n"
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("config.yml", "log:\n  level: info\ninstances:\n"+instance("a"))
+	if err := os.Mkdir(filepath.Join(dir, "conf.d"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	write("conf.d/team1.yml", "instances:\n"+instance("b")+instance("c"))
+	write("conf.d/team2.yml", "instances:\n"+instance("d"))
+	write("conf.d/empty.yml", "")
+	write("conf.d/notes.txt", "ignored")
+
+	ch := &Handler{File: filepath.Join(dir, "config.yml"), Dir: filepath.Join(dir, "conf.d")}
+	c, err := ch.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, instance := range c.Instances {
+		ids = append(ids, instance.InstanceId)
+	}
+	if got := strings.Join(ids, ","); got != "a,b,c,d" {
+		t.Errorf("expected instances a,b,c,d, got %s", got)
+	}
+
+	// The main file is optional with a directory.
+	ch.File = filepath.Join(dir, "missing.yml")
+	if c, err := ch.LoadConfig(); err != nil || len(c.Instances) != 3 {
+		t.Errorf("expected 3 instances without the main file, got %v", err)
+	}
+	ch.File = filepath.Join(dir, "config.yml")
+
+	// Duplicates are reported along with the other problems, naming both
+	// files.
+	write("conf.d/team3.yml", "instances:\n"+instance("a")+"    sslmode: bogus\n")
+	_, err = ch.LoadConfig()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 2 {
+		t.Fatalf("expected a duplicate instance_id and an sslmode error, got %v", err)
+	}
+	for _, file := range []string{"config.yml", "team3.yml"} {
+		if !strings.Contains(verrs[0].Msg, file) {
+			t.Errorf("expected the duplicate instance_id error to name %s, got %q", file, verrs[0].Msg)
+		}
+	}
+
+	// Only instances can be added from the directory.
+	write("conf.d/team3.yml", "log:\n  level: debug\n")
+	if _, err := ch.LoadConfig(); err == nil {
+		t.Error("expected an error for a log section in the config directory")
+	}
+}
+
+func TestWriteConfigFile(t *testing.T) {
+	ch := &Handler{File: filepath.Join(t.TempDir(), "config.yml")}
+	ch.WriteConfigFile(Config{Instances: []Instance{validInstance("a")}})
+	ch.WriteConfigFile(Config{Instances: []Instance{validInstance("b")}})
+
+	fi, err := os.Stat(ch.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected mode 0600, got %o", perm)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(ch.File))
+	if len(entries) != 1 {
+		t.Errorf("expected only the config file to be left, got %d files", len(entries))
+	}
+
+	c, err := ch.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Instances) != 1 || c.Instances[0].InstanceId != "b" {
+		t.Errorf("expected the second config, got %+v", c.Instances)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("GS_TEST_HOST", "db.example")
+	t.Setenv("GS_TEST_EMPTY", "")
+
+	c := &Config{
+		Log: Log{Dir: "${GS_TEST_LOG_DIR:-/var/log/gs}"},
+		Instances: []Instance{{
+			InstanceId: "a",
+			Host:       "${GS_TEST_HOST}",
+			Port:       "${GS_TEST_PORT:-5432}",
+			Db:         "${GS_TEST_EMPTY:-postgres}",
+			User:       "${GS_TEST_EMPTY}",
+			Password:   "p$w$${GS_TEST_HOST}",
+			Labels:     map[string]string{"host": "${GS_TEST_HOST}"},
+		}},
+	}
+	if err := c.expandEnv(); err != nil {
+		t.Fatal(err)
+	}
+	want := Instance{
+		InstanceId: "a",
+		Host:       "db.example",
+		Port:       "5432",
+		Db:         "postgres",
+		Password:   "p$w${GS_TEST_HOST}",
+		Labels:     map[string]string{"host": "db.example"},
+	}
+	if !reflect.DeepEqual(c.Instances[0], want) {
+		t.Errorf("expected %+v, got %+v", want, c.Instances[0])
+	}
+	if c.Log.Dir != "/var/log/gs" {
+		t.Errorf("expected the default log dir, got %q", c.Log.Dir)
+	}
+
+	c = &Config{Instances: []Instance{validInstance("a"), validInstance("b")}}
+	c.Instances[1].Host = "${GS_TEST_UNSET}"
+	err := c.expandEnv()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 1 {
+		t.Fatalf("expected 1 error, got %v", err)
+	}
+	if got := verrs[0].Error(); got != "instances[1].host: environment variable GS_TEST_UNSET is not set and has no default" {
+		t.Errorf("unexpected error %q", got)
+	}
+}
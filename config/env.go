@@ -0,0 +1,124 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// envRE matches ${VAR} and ${VAR:-default}, and $${ escaping a literal ${.
+var envRE = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnv replaces environment variable references in every string of the
+// log section and of the instances. Like in a shell, ${VAR:-default} gives
+// default when VAR is unset or empty; ${VAR} without a default is an error
+// if VAR is unset. The returned error is a ValidationErrors.
+func (c *Config) expandEnv() error {
+	var errs ValidationErrors
+	expandStrings(reflect.ValueOf(&c.Log).Elem(), "log.", func(field string, err error) {
+		errs = append(errs, ValidationError{Index: -1, Field: field, Msg: err.Error()})
+	})
+	for i := range c.Instances {
+		expandStrings(reflect.ValueOf(&c.Instances[i]).Elem(), "", func(field string, err error) {
+			errs = append(errs, ValidationError{Index: i, Field: field, Msg: err.Error()})
+		})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// expandStrings expands the strings, string slices, string maps and nested
+// structs among the fields of the struct v, reporting failures by the YAML
+// name of the field prefixed with prefix.
+func expandStrings(v reflect.Value, prefix string, report func(field string, err error)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		name := prefix + strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			if s, err := expandString(f.String()); err != nil {
+				report(name, err)
+			} else {
+				f.SetString(s)
+			}
+		case reflect.Slice:
+			if f.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			for j := 0; j < f.Len(); j++ {
+				if s, err := expandString(f.Index(j).String()); err != nil {
+					report(fmt.Sprintf("%s[%d]", name, j), err)
+				} else {
+					f.Index(j).SetString(s)
+				}
+			}
+		case reflect.Map:
+			if f.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			iter := f.MapRange()
+			for iter.Next() {
+				if s, err := expandString(iter.Value().String()); err != nil {
+					report(fmt.Sprintf("%s.%v", name, iter.Key()), err)
+				} else {
+					f.SetMapIndex(iter.Key(), reflect.ValueOf(s).Convert(f.Type().Elem()))
+				}
+			}
+		case reflect.Struct:
+			expandStrings(f, name+".", report)
+		}
+	}
+}
+
+// expandString expands the environment variable references in s.
+func expandString(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var missing []string
+	expanded := envRE.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+		m := envRE.FindStringSubmatch(ref)
+		value, ok := os.LookupEnv(m[1])
+		if m[2] != "" && value == "" {
+			return m[3]
+		}
+		if !ok {
+			missing = append(missing, m[1])
+		}
+		return value
+	})
+	switch len(missing) {
+	case 0:
+		return expanded, nil
+	case 1:
+		return "", fmt.Errorf("environment variable %s is not set and has no default", missing[0])
+	default:
+		return "", fmt.Errorf("environment variables %s are not set and have no default", strings.Join(missing, ", "))
+	}
+}
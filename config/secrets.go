@@ -0,0 +1,74 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// passwordCommandTimeout bounds how long a password_command may run.
+const passwordCommandTimeout = 10 * time.Second
+
+// ResolvePassword returns the instance's password from whichever source is
+// configured. File, environment and command sources are read on every call
+// so that rotated credentials are picked up without a reload.
+func (i *Instance) ResolvePassword() (string, error) {
+	switch {
+	case i.PasswordFile != "":
+		data, err := os.ReadFile(i.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("reading password_file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case i.PasswordEnv != "":
+		password, ok := os.LookupEnv(i.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("password_env: environment variable %s is not set", i.PasswordEnv)
+		}
+		return password, nil
+	case i.PasswordCommand != "":
+		return runPasswordCommand(i.PasswordCommand)
+	default:
+		return i.Password, nil
+	}
+}
+
+// runPasswordCommand runs command through the system shell and returns its
+// standard output without the trailing newline.
+func runPasswordCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("password_command failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logLevels are the level names accepted by the exporter's logger.
+var logLevels = []string{"trace", "debug", "info", "warn", "warning", "error", "fatal", "panic"}
+
+// logFormats are the accepted log formats, logfmt being an alias for text.
+var logFormats = []string{"text", "logfmt", "json"}
+
+// sslModes are the accepted sslmode settings.
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// targetSessionAttrs are the accepted target_session_attrs settings.
+var targetSessionAttrs = []string{"any", "read-write"}
+
+// labelNameRE matches valid Prometheus label names.
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// ValidationError is a single problem found in the config. Index is the
+// position of the offending instance, or -1 for settings outside instances.
+type ValidationError struct {
+	Index int
+	Field string
+	Msg   string
+}
+
+func (e ValidationError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("%s: %s", e.Field, e.Msg)
+	}
+	return fmt.Sprintf("instances[%d].%s: %s", e.Index, e.Field, e.Msg)
+}
+
+// ValidationErrors holds every problem found by Validate.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the config for problems that decoding alone can't catch.
+// It doesn't stop at the first problem; the returned error is a
+// ValidationErrors listing all of them.
+func (c *Config) Validate() error {
+	return c.validate(nil)
+}
+
+// validate is Validate that also checks collector names against
+// knownCollectors, unless it is empty.
+func (c *Config) validate(knownCollectors []string) error {
+	var errs ValidationErrors
+	add := func(index int, field, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Index: index, Field: field, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if c.Log.Level != "" && !containsFold(logLevels, c.Log.Level) {
+		add(-1, "log.level", "unsupported log level %q, must be one of %s", c.Log.Level, strings.Join(logLevels, ", "))
+	}
+	if c.Log.MaxAge < 0 {
+		add(-1, "log.max_age", "must not be negative, got %d", c.Log.MaxAge)
+	}
+	if c.Log.Format != "" && !containsFold(logFormats, c.Log.Format) {
+		add(-1, "log.format", "unsupported log format %q, must be one of %s", c.Log.Format, strings.Join(logFormats, ", "))
+	}
+	if c.Log.RotationTime < 0 {
+		add(-1, "log.rotation_time", "must not be negative, got %s", c.Log.RotationTime)
+	}
+	if c.Collector.Timeout < 0 {
+		add(-1, "collector.timeout", "must not be negative, got %s", c.Collector.Timeout)
+	}
+	if c.Pool.MaxOpenConns < 0 {
+		add(-1, "pool.max_open_conns", "must not be negative, got %d", c.Pool.MaxOpenConns)
+	}
+	if c.Pool.MaxIdleConns < 0 {
+		add(-1, "pool.max_idle_conns", "must not be negative, got %d", c.Pool.MaxIdleConns)
+	}
+	if c.Pool.MaxIdleTime < 0 {
+		add(-1, "pool.max_idle_time", "must not be negative, got %s", c.Pool.MaxIdleTime)
+	}
+	validatePerCollector := func(field, what string, values map[string]time.Duration) {
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if len(knownCollectors) > 0 && !contains(knownCollectors, name) {
+				add(-1, field, "unknown collector %q", name)
+			} else if value := values[name]; value <= 0 {
+				add(-1, field, "%s of collector %q must be positive, got %s", what, name, value)
+			}
+		}
+	}
+	validatePerCollector("collector.timeouts", "timeout", c.Collector.Timeouts)
+	if c.Background.Interval < 0 {
+		add(-1, "background.interval", "must not be negative, got %s", c.Background.Interval)
+	}
+	validatePerCollector("background.intervals", "interval", c.Background.Intervals)
+
+	seen := make(map[string]int, len(c.Instances))
+	for i, instance := range c.Instances {
+		if instance.InstanceId == "" {
+			add(i, "instance_id", "is required")
+		} else if first, ok := seen[instance.InstanceId]; ok && len(c.sources) == len(c.Instances) {
+			add(i, "instance_id", "duplicate instance_id %q in %q, already used by instances[%d] in %q", instance.InstanceId, c.sources[i], first, c.sources[first])
+		} else if ok {
+			add(i, "instance_id", "duplicate instance_id %q, already used by instances[%d]", instance.InstanceId, first)
+		} else {
+			seen[instance.InstanceId] = i
+		}
+
+		hosts := strings.Split(instance.Host, ",")
+		if instance.Host == "" {
+			add(i, "host", "is required")
+		} else if contains(hosts, "") {
+			add(i, "host", "malformed list %q, expected comma separated hosts", instance.Host)
+		}
+		ports := strings.Split(instance.Port, ",")
+		for _, p := range ports {
+			if port, err := strconv.Atoi(p); err != nil {
+				add(i, "port", "must be numeric, got %q", p)
+			} else if port < 1 || port > 65535 {
+				add(i, "port", "must be between 1 and 65535, got %d", port)
+			}
+		}
+		if len(ports) > 1 && len(ports) != len(hosts) {
+			add(i, "port", "must list one port for all hosts or one per host, got %d ports for %d hosts", len(ports), len(hosts))
+		}
+		if instance.Db == "" {
+			add(i, "db", "is required")
+		}
+		if instance.User == "" {
+			add(i, "user", "is required")
+		}
+		var sources []string
+		for field, value := range map[string]string{
+			"password":         instance.Password,
+			"password_file":    instance.PasswordFile,
+			"password_env":     instance.PasswordEnv,
+			"password_command": instance.PasswordCommand,
+		} {
+			if value != "" {
+				sources = append(sources, field)
+			}
+		}
+		if len(sources) > 1 {
+			sort.Strings(sources)
+			add(i, "password", "only one password source may be set, got %s", strings.Join(sources, ", "))
+		}
+		if instance.SSLMode != "" && !contains(sslModes, instance.SSLMode) {
+			add(i, "sslmode", "unsupported sslmode %q, must be one of %s", instance.SSLMode, strings.Join(sslModes, ", "))
+		}
+		if (instance.SSLCert == "") != (instance.SSLKey == "") {
+			add(i, "sslcert", "sslcert and sslkey must be set together")
+		}
+		if instance.ConnectTimeout < 0 {
+			add(i, "connect_timeout", "must not be negative, got %s", instance.ConnectTimeout)
+		}
+		if instance.TargetSessionAttrs != "" && !contains(targetSessionAttrs, instance.TargetSessionAttrs) {
+			add(i, "target_session_attrs", "unsupported target_session_attrs %q, must be one of %s", instance.TargetSessionAttrs, strings.Join(targetSessionAttrs, ", "))
+		}
+		for _, dbs := range []struct{ field, list string }{
+			{"exclude_dbs", instance.ExcludeDbs},
+			{"include_dbs", instance.IncludeDbs},
+		} {
+			if dbs.list != "" && len(splitDbs(dbs.list)) != len(strings.Split(dbs.list, ",")) {
+				add(i, dbs.field, "malformed list %q, expected comma separated database names", dbs.list)
+			}
+		}
+		for _, db := range instance.IncludedDatabases() {
+			if contains(instance.ExcludedDatabases(), db) {
+				add(i, "include_dbs", "database %q is both included and excluded", db)
+			}
+		}
+		for _, name := range instance.Collectors.Enable {
+			if len(knownCollectors) > 0 && !contains(knownCollectors, name) {
+				add(i, "collectors.enable", "unknown collector %q", name)
+			} else if contains(instance.Collectors.Disable, name) {
+				add(i, "collectors.enable", "collector %q is both enabled and disabled", name)
+			}
+		}
+		for _, name := range instance.Collectors.Disable {
+			if len(knownCollectors) > 0 && !contains(knownCollectors, name) {
+				add(i, "collectors.disable", "unknown collector %q", name)
+			}
+		}
+		names := make([]string, 0, len(instance.Labels))
+		for name := range instance.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			switch {
+			case !labelNameRE.MatchString(name) || strings.HasPrefix(name, "__"):
+				add(i, "labels", "invalid label name %q", name)
+			case name == "instance_id":
+				add(i, "labels", "label %q is set by the exporter", name)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
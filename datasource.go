@@ -1,129 +1,216 @@
-// Copyright 2021 The Prometheus Authors
-// Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this file except in compliance with the License.
-// You may obtain a copy of the License at
-//
-// http://www.apache.org/licenses/LICENSE-2.0
-//
-// Unless required by applicable law or agreed to in writing, software
-// distributed under the License is distributed on an "AS IS" BASIS,
-// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-// See the License for the specific language governing permissions and
-// limitations under the License.
-
-package main
-
-import (
-	"fmt"
-	"net/url"
-	"regexp"
-	"strings"
-
-	"github.com/prometheus-community/gaussdb_exporter/utils"
-	"github.com/prometheus/client_golang/prometheus"
-)
-
-func (e *Exporter) discoverDatabaseDSNs() []string {
-	// connstring syntax is complex (and not sure if even regular).
-	// we don't need to parse it, so just superficially validate that it starts
-	// with a valid-ish keyword pair
-	connstringRe := regexp.MustCompile(`^ *[a-zA-Z0-9]+ *= *[^= ]+`)
-
-	dsns := make(map[string]struct{})
-	for _, dsn := range e.dsn {
-		var dsnURI *url.URL
-		var dsnConnstring string
-
-		if strings.HasPrefix(dsn, "postgresql://") || strings.HasPrefix(dsn, "postgres://") {
-			var err error
-			dsnURI, err = url.Parse(dsn)
-			if err != nil {
-				utils.GetLogger().Error("Unable to parse DSN as URI", "dsn", loggableDSN(dsn), "err", err)
-				continue
-			}
-		} else if connstringRe.MatchString(dsn) {
-			dsnConnstring = dsn
-		} else {
-			utils.GetLogger().Error("Unable to parse DSN as either URI or connstring", "dsn", loggableDSN(dsn))
-			continue
-		}
-
-		server, err := e.servers.GetServer(dsn)
-		if err != nil {
-			utils.GetLogger().Error("Error opening connection to database", "dsn", loggableDSN(dsn), "err", err)
-			continue
-		}
-		dsns[dsn] = struct{}{}
-
-		// If autoDiscoverDatabases is true, set first dsn as master database (Default: false)
-		server.master = true
-
-		databaseNames, err := queryDatabases(server)
-		if err != nil {
-			utils.GetLogger().Error("Error querying databases", "dsn", loggableDSN(dsn), "err", err)
-			continue
-		}
-		for _, databaseName := range databaseNames {
-			if contains(e.excludeDatabases, databaseName) {
-				continue
-			}
-
-			if len(e.includeDatabases) != 0 && !contains(e.includeDatabases, databaseName) {
-				continue
-			}
-
-			if dsnURI != nil {
-				dsnURI.Path = databaseName
-				dsn = dsnURI.String()
-			} else {
-				// replacing one dbname with another is complicated.
-				// just append new dbname to override.
-				dsn = fmt.Sprintf("%s dbname=%s", dsnConnstring, databaseName)
-			}
-			dsns[dsn] = struct{}{}
-		}
-	}
-
-	result := make([]string, len(dsns))
-	index := 0
-	for dsn := range dsns {
-		result[index] = dsn
-		index++
-	}
-
-	return result
-}
-
-func (e *Exporter) scrapeDSN(ch chan<- prometheus.Metric, dsn string) error {
-	server, err := e.servers.GetServer(dsn)
-
-	if err != nil {
-		return &ErrorConnectToServer{fmt.Sprintf("Error opening connection to database (%s): %s", loggableDSN(dsn), err.Error())}
-	}
-
-	// Check if autoDiscoverDatabases is false, set dsn as master database (Default: false)
-	if !e.autoDiscoverDatabases {
-		server.master = true
-	}
-
-	// Check if map versions need to be updated
-	if err := e.checkMapVersions(ch, server); err != nil {
-		utils.GetLogger().Warn("Proceeding with outdated query maps, as the Postgres version could not be determined", "err", err)
-	}
-
-	return server.Scrape(ch, e.disableSettingsMetrics)
-}
-
-// try to get the DataSource
-func getDataSourceById(instanceId string) (string, error) {
-	cfg := cfgHandler.GetConfig()
-	instances := cfg.Instances
-	for i := 0; i < len(instances); i++ {
-		instance := instances[i]
-		if instanceId == instance.InstanceId {
-			return fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s sslmode=disable", instance.Host, instance.Port, instance.Db, instance.User, instance.Password), nil
-		}
-	}
-
-	return "", fmt.Errorf("根据实例ID（instance_id=%s）并没有找到对应配置的实例！", instanceId)
-}
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus-community/gaussdb_exporter/collector"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"config"
+)
+
+func (e *Exporter) discoverDatabaseDSNs(ctx context.Context) []string {
+	// connstring syntax is complex (and not sure if even regular).
+	// we don't need to parse it, so just superficially validate that it starts
+	// with a valid-ish keyword pair
+	connstringRe := regexp.MustCompile(`^ *[a-zA-Z0-9]+ *= *[^= ]+`)
+
+	dsns := make(map[string]struct{})
+	for _, dsn := range e.dsn {
+		var dsnURI *url.URL
+		var dsnConnstring string
+
+		if strings.HasPrefix(dsn, "postgresql://") || strings.HasPrefix(dsn, "postgres://") {
+			var err error
+			dsnURI, err = url.Parse(dsn)
+			if err != nil {
+				e.logger.Error("Unable to parse DSN as URI", "dsn", loggableDSN(dsn), "err", err)
+				continue
+			}
+		} else if connstringRe.MatchString(dsn) {
+			dsnConnstring = dsn
+		} else {
+			e.logger.Error("Unable to parse DSN as either URI or connstring", "dsn", loggableDSN(dsn))
+			continue
+		}
+
+		server, err := e.servers.GetServer(ctx, dsn)
+		if err != nil {
+			e.logger.Error("Error opening connection to database", "dsn", loggableDSN(dsn), "err", err)
+			continue
+		}
+		dsns[dsn] = struct{}{}
+
+		// If autoDiscoverDatabases is true, set first dsn as master database (Default: false)
+		// Shared servers are always master and may be in use by other scrapes.
+		if !server.pooled {
+			server.master = true
+		}
+
+		databaseNames, err := queryDatabases(ctx, server)
+		if err != nil {
+			server.logger.Error("Error querying databases", "dsn", loggableDSN(dsn), "err", err)
+			continue
+		}
+		for _, databaseName := range databaseNames {
+			if e.databases.Skip(databaseName) {
+				continue
+			}
+
+			if dsnURI != nil {
+				dsnURI.Path = databaseName
+				dsn = dsnURI.String()
+			} else {
+				// replacing one dbname with another is complicated.
+				// just append new dbname to override.
+				dsn = fmt.Sprintf("%s dbname=%s", dsnConnstring, quoteConnValue(databaseName))
+			}
+			dsns[dsn] = struct{}{}
+		}
+	}
+
+	result := make([]string, len(dsns))
+	index := 0
+	for dsn := range dsns {
+		result[index] = dsn
+		index++
+	}
+
+	return result
+}
+
+func (e *Exporter) scrapeDSN(ctx context.Context, ch chan<- prometheus.Metric, dsn string) error {
+	server, err := e.servers.GetServer(ctx, dsn)
+
+	if err != nil {
+		return &ErrorConnectToServer{fmt.Sprintf("Error opening connection to database (%s): %s", loggableDSN(dsn), err.Error())}
+	}
+
+	// Check if autoDiscoverDatabases is false, set dsn as master database (Default: false)
+	// Shared servers are always master and may be in use by other scrapes.
+	if !e.autoDiscoverDatabases && !server.pooled {
+		server.master = true
+	}
+
+	// Check if map versions need to be updated
+	if err := e.checkMapVersions(ctx, ch, server); err != nil {
+		server.logger.Warn("Proceeding with outdated query maps, as the Postgres version could not be determined", "err", err)
+	}
+
+	return server.Scrape(ctx, ch, e.disableSettingsMetrics, e.databases)
+}
+
+// instanceLabels returns the labels configured to be added to every metric
+// scraped from instanceId, besides its instance_id.
+func instanceLabels(instanceId string) prometheus.Labels {
+	labels := prometheus.Labels{}
+	if instance, ok := getInstanceById(instanceId); ok {
+		for name, value := range instance.Labels {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// collectorOverrides returns the collectors turned on or off for instanceId.
+func collectorOverrides(instanceId string) collector.Overrides {
+	instance, _ := getInstanceById(instanceId)
+	return collector.Overrides{
+		Enable:  instance.Collectors.Enable,
+		Disable: instance.Collectors.Disable,
+	}
+}
+
+// databaseFilter returns the databases the per-database metrics of
+// instanceId cover.
+func databaseFilter(instanceId string) collector.DatabaseFilter {
+	instance, _ := getInstanceById(instanceId)
+	return collector.DatabaseFilter{
+		Exclude: instance.ExcludedDatabases(),
+		Include: instance.IncludedDatabases(),
+	}
+}
+
+// getInstanceById returns the configured instance with instanceId.
+func getInstanceById(instanceId string) (config.Instance, bool) {
+	if cfg := cfgHandler.GetConfig(); cfg != nil {
+		for _, instance := range cfg.Instances {
+			if instance.InstanceId == instanceId {
+				return instance, true
+			}
+		}
+	}
+	return config.Instance{}, false
+}
+
+// try to get the DataSource
+func getDataSourceById(instanceId string) (string, error) {
+	cfg := cfgHandler.GetConfig()
+	instances := cfg.Instances
+	for i := 0; i < len(instances); i++ {
+		instance := instances[i]
+		if instanceId == instance.InstanceId {
+			password, err := instance.ResolvePassword()
+			if err != nil {
+				return "", fmt.Errorf("无法获取实例（instance_id=%s）的密码: %w", instanceId, err)
+			}
+			return buildDSN(instance, password), nil
+		}
+	}
+
+	return "", fmt.Errorf("根据实例ID（instance_id=%s）并没有找到对应配置的实例！", instanceId)
+}
+
+// buildDSN returns the connection string of instance, with every value
+// quoted.
+func buildDSN(instance config.Instance, password string) string {
+	sslmode := instance.SSLMode
+	if sslmode == "" {
+		sslmode = "disable"
+	}
+	settings := []struct{ key, value string }{
+		{"host", instance.Host},
+		{"port", instance.Port},
+		{"dbname", instance.Db},
+		{"user", instance.User},
+		{"password", password},
+		{"sslmode", sslmode},
+		{"sslrootcert", instance.SSLRootCert},
+		{"sslcert", instance.SSLCert},
+		{"sslkey", instance.SSLKey},
+		{"application_name", instance.ApplicationName},
+		{"target_session_attrs", instance.TargetSessionAttrs},
+	}
+	if instance.ConnectTimeout > 0 {
+		// The driver takes whole seconds; round up rather than to no limit.
+		seconds := int64(math.Ceil(instance.ConnectTimeout.Seconds()))
+		settings = append(settings, struct{ key, value string }{"connect_timeout", strconv.FormatInt(seconds, 10)})
+	}
+
+	var pairs []string
+	for _, s := range settings {
+		if s.value != "" {
+			pairs = append(pairs, s.key+"="+quoteConnValue(s.value))
+		}
+	}
+	return strings.Join(pairs, " ")
+}
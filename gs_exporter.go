@@ -1,716 +1,756 @@
-// Copyright 2021 The Prometheus Authors
-// Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this file except in compliance with the License.
-// You may obtain a copy of the License at
-//
-// http://www.apache.org/licenses/LICENSE-2.0
-//
-// Unless required by applicable law or agreed to in writing, software
-// distributed under the License is distributed on an "AS IS" BASIS,
-// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-// See the License for the specific language governing permissions and
-// limitations under the License.
-
-package main
-
-import (
-	"crypto/sha256"
-	"database/sql"
-	"errors"
-	"fmt"
-	"math"
-	"os"
-	"regexp"
-	"strconv"
-	"strings"
-	"time"
-
-	"github.com/blang/semver/v4"
-	"github.com/prometheus-community/gaussdb_exporter/utils"
-	"github.com/prometheus/client_golang/prometheus"
-)
-
-// ColumnUsage should be one of several enum values which describe how a
-// queried row is to be converted to a Prometheus metric.
-type ColumnUsage int
-
-const (
-	// DISCARD ignores a column
-	DISCARD ColumnUsage = iota
-	// LABEL identifies a column as a label
-	LABEL ColumnUsage = iota
-	// COUNTER identifies a column as a counter
-	COUNTER ColumnUsage = iota
-	// GAUGE identifies a column as a gauge
-	GAUGE ColumnUsage = iota
-	// MAPPEDMETRIC identifies a column as a mapping of text values
-	MAPPEDMETRIC ColumnUsage = iota
-	// DURATION identifies a column as a text duration (and converted to milliseconds)
-	DURATION ColumnUsage = iota
-	// HISTOGRAM identifies a column as a histogram
-	HISTOGRAM ColumnUsage = iota
-)
-
-// UnmarshalYAML implements the yaml.Unmarshaller interface.
-func (cu *ColumnUsage) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	var value string
-	if err := unmarshal(&value); err != nil {
-		return err
-	}
-
-	columnUsage, err := stringToColumnUsage(value)
-	if err != nil {
-		return err
-	}
-
-	*cu = columnUsage
-	return nil
-}
-
-// MappingOptions is a copy of ColumnMapping used only for parsing
-type MappingOptions struct {
-	Usage             string             `yaml:"usage"`
-	Description       string             `yaml:"description"`
-	Mapping           map[string]float64 `yaml:"metric_mapping"` // Optional column mapping for MAPPEDMETRIC
-	SupportedVersions semver.Range       `yaml:"pg_version"`     // Semantic version ranges which are supported. Unsupported columns are not queried (internally converted to DISCARD).
-}
-
-// Mapping represents a set of MappingOptions
-type Mapping map[string]MappingOptions
-
-// Regex used to get the "short-version" from the postgres version field.
-//var versionRegex = regexp.MustCompile(`^\w+ ((\d+)(\.\d+)?(\.\d+)?)`)
-//KingbaseES V008R006C008B0014
-//var versionRegex = regexp.MustCompile(`^KingbaseES ([0-9a-zA-Z]*)`)
-var versionRegex = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
-var lowestSupportedVersion = semver.MustParse("5.0.0")
-
-// Parses the version of postgres into the short version string we can use to
-// match behaviors.
-func parseVersion(versionString string) (semver.Version, error) {
-	submatches := versionRegex.FindStringSubmatch(versionString)
-	if len(submatches) > 2 {
-		major, _ := strconv.Atoi(submatches[1])
-		minor, _ := strconv.Atoi(submatches[2])
-		patch, _ := strconv.Atoi(submatches[3])
-		version := fmt.Sprintf("%d.%d.%d", major, minor, patch)
-		return semver.ParseTolerant(version)
-	}
-	return semver.Version{},
-		errors.New(fmt.Sprintln("Could not find a gaussdb version in string:", versionString))
-}
-
-// ColumnMapping is the user-friendly representation of a prometheus descriptor map
-type ColumnMapping struct {
-	usage             ColumnUsage        `yaml:"usage"`
-	description       string             `yaml:"description"`
-	mapping           map[string]float64 `yaml:"metric_mapping"` // Optional column mapping for MAPPEDMETRIC
-	supportedVersions semver.Range       `yaml:"pg_version"`     // Semantic version ranges which are supported. Unsupported columns are not queried (internally converted to DISCARD).
-}
-
-// UnmarshalYAML implements yaml.Unmarshaller
-func (cm *ColumnMapping) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	type plain ColumnMapping
-	return unmarshal((*plain)(cm))
-}
-
-// intermediateMetricMap holds the partially loaded metric map parsing.
-// This is mainly so we can parse cacheSeconds around.
-type intermediateMetricMap struct {
-	columnMappings map[string]ColumnMapping
-	master         bool
-	cacheSeconds   uint64
-}
-
-// MetricMapNamespace groups metric maps under a shared set of labels.
-type MetricMapNamespace struct {
-	labels         []string             // Label names for this namespace
-	columnMappings map[string]MetricMap // Column mappings in this namespace
-	master         bool                 // Call query only for master database
-	cacheSeconds   uint64               // Number of seconds this metric namespace can be cached. 0 disables.
-}
-
-// MetricMap stores the prometheus metric description which a given column will
-// be mapped to by the collector
-type MetricMap struct {
-	discard    bool                              // Should metric be discarded during mapping?
-	histogram  bool                              // Should metric be treated as a histogram?
-	vtype      prometheus.ValueType              // Prometheus valuetype
-	desc       *prometheus.Desc                  // Prometheus descriptor
-	conversion func(interface{}) (float64, bool) // Conversion function to turn PG result into float64
-}
-
-// ErrorConnectToServer is a connection to PgSQL server error
-type ErrorConnectToServer struct {
-	Msg string
-}
-
-// Error returns error
-func (e *ErrorConnectToServer) Error() string {
-	return e.Msg
-}
-
-// TODO: revisit this with the semver system
-func dumpMaps() {
-	// TODO: make this function part of the exporter
-	for name, cmap := range builtinMetricMaps {
-		query, ok := queryOverrides[name]
-		if !ok {
-			fmt.Println(name)
-		} else {
-			for _, queryOverride := range query {
-				fmt.Println(name, queryOverride.versionRange, queryOverride.query)
-			}
-		}
-
-		for column, details := range cmap.columnMappings {
-			fmt.Printf("  %-40s %v\n", column, details)
-		}
-		fmt.Println()
-	}
-}
-
-var builtinMetricMaps = map[string]intermediateMetricMap{
-	"pg_stat_database_conflicts": {
-		map[string]ColumnMapping{
-			"datid":            {LABEL, "OID of a database", nil, nil},
-			"datname":          {LABEL, "Name of this database", nil, nil},
-			"confl_tablespace": {COUNTER, "Number of queries in this database that have been canceled due to dropped tablespaces", nil, nil},
-			"confl_lock":       {COUNTER, "Number of queries in this database that have been canceled due to lock timeouts", nil, nil},
-			"confl_snapshot":   {COUNTER, "Number of queries in this database that have been canceled due to old snapshots", nil, nil},
-			"confl_bufferpin":  {COUNTER, "Number of queries in this database that have been canceled due to pinned buffers", nil, nil},
-			"confl_deadlock":   {COUNTER, "Number of queries in this database that have been canceled due to deadlocks", nil, nil},
-		},
-		true,
-		0,
-	},
-	"pg_stat_replication": {
-		map[string]ColumnMapping{
-			"procpid":          {DISCARD, "Process ID of a WAL sender process", nil, semver.MustParseRange("<9.2.0")},
-			"pid":              {DISCARD, "Process ID of a WAL sender process", nil, semver.MustParseRange(">=9.2.0")},
-			"usesysid":         {DISCARD, "OID of the user logged into this WAL sender process", nil, nil},
-			"usename":          {DISCARD, "Name of the user logged into this WAL sender process", nil, nil},
-			"application_name": {LABEL, "Name of the application that is connected to this WAL sender", nil, nil},
-			"client_addr":      {LABEL, "IP address of the client connected to this WAL sender. If this field is null, it indicates that the client is connected via a Unix socket on the server machine.", nil, nil},
-			"client_hostname":  {DISCARD, "Host name of the connected client, as reported by a reverse DNS lookup of client_addr. This field will only be non-null for IP connections, and only when log_hostname is enabled.", nil, nil},
-			"client_port":      {DISCARD, "TCP port number that the client is using for communication with this WAL sender, or -1 if a Unix socket is used", nil, nil},
-			"backend_start": {DISCARD, "with time zone	Time when this process was started, i.e., when the client connected to this WAL sender", nil, nil},
-			"backend_xmin":             {DISCARD, "The current backend's xmin horizon.", nil, nil},
-			"state":                    {LABEL, "Current WAL sender state", nil, nil},
-			"sent_location":            {DISCARD, "Last transaction log position sent on this connection", nil, semver.MustParseRange("<10.0.0")},
-			"write_location":           {DISCARD, "Last transaction log position written to disk by this standby server", nil, semver.MustParseRange("<10.0.0")},
-			"flush_location":           {DISCARD, "Last transaction log position flushed to disk by this standby server", nil, semver.MustParseRange("<10.0.0")},
-			"replay_location":          {DISCARD, "Last transaction log position replayed into the database on this standby server", nil, semver.MustParseRange("<10.0.0")},
-			"sent_lsn":                 {DISCARD, "Last transaction log position sent on this connection", nil, semver.MustParseRange(">=10.0.0")},
-			"write_lsn":                {DISCARD, "Last transaction log position written to disk by this standby server", nil, semver.MustParseRange(">=10.0.0")},
-			"flush_lsn":                {DISCARD, "Last transaction log position flushed to disk by this standby server", nil, semver.MustParseRange(">=10.0.0")},
-			"replay_lsn":               {DISCARD, "Last transaction log position replayed into the database on this standby server", nil, semver.MustParseRange(">=10.0.0")},
-			"sync_priority":            {DISCARD, "Priority of this standby server for being chosen as the synchronous standby", nil, nil},
-			"sync_state":               {DISCARD, "Synchronous state of this standby server", nil, nil},
-			"slot_name":                {LABEL, "A unique, cluster-wide identifier for the replication slot", nil, semver.MustParseRange(">=9.2.0")},
-			"plugin":                   {DISCARD, "The base name of the shared object containing the output plugin this logical slot is using, or null for physical slots", nil, nil},
-			"slot_type":                {DISCARD, "The slot type - physical or logical", nil, nil},
-			"datoid":                   {DISCARD, "The OID of the database this slot is associated with, or null. Only logical slots have an associated database", nil, nil},
-			"database":                 {DISCARD, "The name of the database this slot is associated with, or null. Only logical slots have an associated database", nil, nil},
-			"active":                   {DISCARD, "True if this slot is currently actively being used", nil, nil},
-			"active_pid":               {DISCARD, "Process ID of a WAL sender process", nil, nil},
-			"xmin":                     {DISCARD, "The oldest transaction that this slot needs the database to retain. VACUUM cannot remove tuples deleted by any later transaction", nil, nil},
-			"catalog_xmin":             {DISCARD, "The oldest transaction affecting the system catalogs that this slot needs the database to retain. VACUUM cannot remove catalog tuples deleted by any later transaction", nil, nil},
-			"restart_lsn":              {DISCARD, "The address (LSN) of oldest WAL which still might be required by the consumer of this slot and thus won't be automatically removed during checkpoints", nil, nil},
-			"pg_current_xlog_location": {DISCARD, "pg_current_xlog_location", nil, nil},
-			"pg_current_wal_lsn":       {DISCARD, "pg_current_xlog_location", nil, semver.MustParseRange(">=10.0.0")},
-			"pg_current_wal_lsn_bytes": {GAUGE, "WAL position in bytes", nil, semver.MustParseRange(">=10.0.0")},
-			"pg_xlog_location_diff":    {GAUGE, "Lag in bytes between master and slave", nil, semver.MustParseRange(">=9.2.0 <10.0.0")},
-			"pg_wal_lsn_diff":          {GAUGE, "Lag in bytes between master and slave", nil, semver.MustParseRange(">=10.0.0")},
-			"confirmed_flush_lsn":      {DISCARD, "LSN position a consumer of a slot has confirmed flushing the data received", nil, nil},
-			"write_lag":                {DISCARD, "Time elapsed between flushing recent WAL locally and receiving notification that this standby server has written it (but not yet flushed it or applied it). This can be used to gauge the delay that synchronous_commit level remote_write incurred while committing if this server was configured as a synchronous standby.", nil, semver.MustParseRange(">=10.0.0")},
-			"flush_lag":                {DISCARD, "Time elapsed between flushing recent WAL locally and receiving notification that this standby server has written and flushed it (but not yet applied it). This can be used to gauge the delay that synchronous_commit level remote_flush incurred while committing if this server was configured as a synchronous standby.", nil, semver.MustParseRange(">=10.0.0")},
-			"replay_lag":               {DISCARD, "Time elapsed between flushing recent WAL locally and receiving notification that this standby server has written, flushed and applied it. This can be used to gauge the delay that synchronous_commit level remote_apply incurred while committing if this server was configured as a synchronous standby.", nil, semver.MustParseRange(">=10.0.0")},
-		},
-		true,
-		0,
-	},
-	"pg_replication_slots": {
-		map[string]ColumnMapping{
-			"slot_name":       {LABEL, "Name of the replication slot", nil, nil},
-			"database":        {LABEL, "Name of the database", nil, nil},
-			"active":          {GAUGE, "Flag indicating if the slot is active", nil, nil},
-			"pg_wal_lsn_diff": {GAUGE, "Replication lag in bytes", nil, nil},
-		},
-		true,
-		0,
-	},
-	"pg_stat_archiver": {
-		map[string]ColumnMapping{
-			"archived_count":     {COUNTER, "Number of WAL files that have been successfully archived", nil, nil},
-			"last_archived_wal":  {DISCARD, "Name of the last WAL file successfully archived", nil, nil},
-			"last_archived_time": {DISCARD, "Time of the last successful archive operation", nil, nil},
-			"failed_count":       {COUNTER, "Number of failed attempts for archiving WAL files", nil, nil},
-			"last_failed_wal":    {DISCARD, "Name of the WAL file of the last failed archival operation", nil, nil},
-			"last_failed_time":   {DISCARD, "Time of the last failed archival operation", nil, nil},
-			"stats_reset":        {DISCARD, "Time at which these statistics were last reset", nil, nil},
-			"last_archive_age":   {GAUGE, "Time in seconds since last WAL segment was successfully archived", nil, nil},
-		},
-		true,
-		0,
-	},
-	"pg_stat_activity": {
-		map[string]ColumnMapping{
-			"datname":          {LABEL, "Name of this database", nil, nil},
-			"state":            {LABEL, "connection state", nil, semver.MustParseRange(">=9.2.0")},
-			"usename":          {LABEL, "connection usename", nil, nil},
-			"application_name": {LABEL, "connection application_name", nil, nil},
-			"count":            {GAUGE, "number of connections in this state", nil, nil},
-			"max_tx_duration":  {GAUGE, "max duration in seconds any active transaction has been running", nil, nil},
-		},
-		true,
-		0,
-	},
-}
-
-// Turn the MetricMap column mapping into a prometheus descriptor mapping.
-func makeDescMap(pgVersion semver.Version, serverLabels prometheus.Labels, metricMaps map[string]intermediateMetricMap) map[string]MetricMapNamespace {
-	var metricMap = make(map[string]MetricMapNamespace)
-
-	for namespace, intermediateMappings := range metricMaps {
-		thisMap := make(map[string]MetricMap)
-
-		namespace = strings.Replace(namespace, "gs", *metricPrefix, 1)
-
-		// Get the constant labels
-		var variableLabels []string
-		for columnName, columnMapping := range intermediateMappings.columnMappings {
-			if columnMapping.usage == LABEL {
-				variableLabels = append(variableLabels, columnName)
-			}
-		}
-
-		for columnName, columnMapping := range intermediateMappings.columnMappings {
-			// Check column version compatibility for the current map
-			// Force to discard if not compatible.
-			if columnMapping.supportedVersions != nil {
-				if !columnMapping.supportedVersions(pgVersion) {
-					// It's very useful to be able to see what columns are being
-					// rejected.
-					utils.GetLogger().Debug("Column is being forced to discard due to version incompatibility", "column", columnName)
-					thisMap[columnName] = MetricMap{
-						discard: true,
-						conversion: func(_ interface{}) (float64, bool) {
-							return math.NaN(), true
-						},
-					}
-					continue
-				}
-			}
-
-			// Determine how to convert the column based on its usage.
-			// nolint: dupl
-			switch columnMapping.usage {
-			case DISCARD, LABEL:
-				thisMap[columnName] = MetricMap{
-					discard: true,
-					conversion: func(_ interface{}) (float64, bool) {
-						return math.NaN(), true
-					},
-				}
-			case COUNTER:
-				thisMap[columnName] = MetricMap{
-					vtype: prometheus.CounterValue,
-					desc:  prometheus.NewDesc(fmt.Sprintf("%s_%s", namespace, columnName), columnMapping.description, variableLabels, serverLabels),
-					conversion: func(in interface{}) (float64, bool) {
-						return dbToFloat64(in)
-					},
-				}
-			case GAUGE:
-				thisMap[columnName] = MetricMap{
-					vtype: prometheus.GaugeValue,
-					desc:  prometheus.NewDesc(fmt.Sprintf("%s_%s", namespace, columnName), columnMapping.description, variableLabels, serverLabels),
-					conversion: func(in interface{}) (float64, bool) {
-						return dbToFloat64(in)
-					},
-				}
-			case HISTOGRAM:
-				thisMap[columnName] = MetricMap{
-					histogram: true,
-					vtype:     prometheus.UntypedValue,
-					desc:      prometheus.NewDesc(fmt.Sprintf("%s_%s", namespace, columnName), columnMapping.description, variableLabels, serverLabels),
-					conversion: func(in interface{}) (float64, bool) {
-						return dbToFloat64(in)
-					},
-				}
-				thisMap[columnName+"_bucket"] = MetricMap{
-					histogram: true,
-					discard:   true,
-				}
-				thisMap[columnName+"_sum"] = MetricMap{
-					histogram: true,
-					discard:   true,
-				}
-				thisMap[columnName+"_count"] = MetricMap{
-					histogram: true,
-					discard:   true,
-				}
-			case MAPPEDMETRIC:
-				thisMap[columnName] = MetricMap{
-					vtype: prometheus.GaugeValue,
-					desc:  prometheus.NewDesc(fmt.Sprintf("%s_%s", namespace, columnName), columnMapping.description, variableLabels, serverLabels),
-					conversion: func(in interface{}) (float64, bool) {
-						text, ok := in.(string)
-						if !ok {
-							return math.NaN(), false
-						}
-
-						val, ok := columnMapping.mapping[text]
-						if !ok {
-							return math.NaN(), false
-						}
-						return val, true
-					},
-				}
-			case DURATION:
-				thisMap[columnName] = MetricMap{
-					vtype: prometheus.GaugeValue,
-					desc:  prometheus.NewDesc(fmt.Sprintf("%s_%s_milliseconds", namespace, columnName), columnMapping.description, variableLabels, serverLabels),
-					conversion: func(in interface{}) (float64, bool) {
-						var durationString string
-						switch t := in.(type) {
-						case []byte:
-							durationString = string(t)
-						case string:
-							durationString = t
-						default:
-							utils.GetLogger().Error("Duration conversion metric was not a string")
-							return math.NaN(), false
-						}
-
-						if durationString == "-1" {
-							return math.NaN(), false
-						}
-
-						d, err := time.ParseDuration(durationString)
-						if err != nil {
-							utils.GetLogger().Error("Failed converting result to metric", "column", columnName, "in", in, "err", err)
-							return math.NaN(), false
-						}
-						return float64(d / time.Millisecond), true
-					},
-				}
-			}
-		}
-
-		metricMap[namespace] = MetricMapNamespace{variableLabels, thisMap, intermediateMappings.master, intermediateMappings.cacheSeconds}
-	}
-
-	return metricMap
-}
-
-type cachedMetrics struct {
-	metrics    []prometheus.Metric
-	lastScrape time.Time
-}
-
-// Exporter collects Postgres metrics. It implements prometheus.Collector.
-type Exporter struct {
-	// Holds a reference to the build in column mappings. Currently this is for testing purposes
-	// only, since it just points to the global.
-	builtinMetricMaps map[string]intermediateMetricMap
-
-	disableDefaultMetrics, disableSettingsMetrics, autoDiscoverDatabases bool
-
-	excludeDatabases []string
-	includeDatabases []string
-	dsn              []string
-	userQueriesPath  string
-	constantLabels   prometheus.Labels
-	duration         prometheus.Gauge
-	error            prometheus.Gauge
-	psqlUp           prometheus.Gauge
-	userQueriesError *prometheus.GaugeVec
-	totalScrapes     prometheus.Counter
-
-	// servers are used to allow re-using the DB connection between scrapes.
-	// servers contains metrics map and query overrides.
-	servers *Servers
-}
-
-// ExporterOpt configures Exporter.
-type ExporterOpt func(*Exporter)
-
-// DisableDefaultMetrics configures default metrics export.
-func DisableDefaultMetrics(b bool) ExporterOpt {
-	return func(e *Exporter) {
-		e.disableDefaultMetrics = b
-	}
-}
-
-// DisableSettingsMetrics configures pg_settings export.
-func DisableSettingsMetrics(b bool) ExporterOpt {
-	return func(e *Exporter) {
-		e.disableSettingsMetrics = b
-	}
-}
-
-// AutoDiscoverDatabases allows scraping all databases on a database server.
-func AutoDiscoverDatabases(b bool) ExporterOpt {
-	return func(e *Exporter) {
-		e.autoDiscoverDatabases = b
-	}
-}
-
-// ExcludeDatabases allows to filter out result from AutoDiscoverDatabases
-func ExcludeDatabases(s []string) ExporterOpt {
-	return func(e *Exporter) {
-		e.excludeDatabases = s
-	}
-}
-
-// IncludeDatabases allows to filter result from AutoDiscoverDatabases
-func IncludeDatabases(s string) ExporterOpt {
-	return func(e *Exporter) {
-		if len(s) > 0 {
-			e.includeDatabases = strings.Split(s, ",")
-		}
-	}
-}
-
-// WithUserQueriesPath configures user's queries path.
-func WithUserQueriesPath(p string) ExporterOpt {
-	return func(e *Exporter) {
-		e.userQueriesPath = p
-	}
-}
-
-// WithConstantLabels configures constant labels.
-func WithConstantLabels(s string) ExporterOpt {
-	return func(e *Exporter) {
-		e.constantLabels = parseConstLabels(s)
-	}
-}
-
-func parseConstLabels(s string) prometheus.Labels {
-	labels := make(prometheus.Labels)
-
-	s = strings.TrimSpace(s)
-	if len(s) == 0 {
-		return labels
-	}
-
-	parts := strings.Split(s, ",")
-	for _, p := range parts {
-		keyValue := strings.Split(strings.TrimSpace(p), "=")
-		if len(keyValue) != 2 {
-			utils.GetLogger().Error(`Wrong constant labels format, should be "key=value"`, "input", p)
-			continue
-		}
-		key := strings.TrimSpace(keyValue[0])
-		value := strings.TrimSpace(keyValue[1])
-		if key == "" || value == "" {
-			continue
-		}
-		labels[key] = value
-	}
-
-	return labels
-}
-
-// NewExporter returns a new PostgreSQL exporter for the provided DSN.
-func NewExporter(dsn []string, opts ...ExporterOpt) *Exporter {
-	e := &Exporter{
-		dsn:               dsn,
-		builtinMetricMaps: builtinMetricMaps,
-	}
-
-	for _, opt := range opts {
-		opt(e)
-	}
-
-	e.setupInternalMetrics()
-	e.servers = NewServers(ServerWithLabels(e.constantLabels))
-
-	return e
-}
-
-func (e *Exporter) setupInternalMetrics() {
-	e.duration = prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace:   namespace,
-		Subsystem:   exporter,
-		Name:        "last_scrape_duration_seconds",
-		Help:        "Duration of the last scrape of metrics from PostgreSQL.",
-		ConstLabels: e.constantLabels,
-	})
-	e.totalScrapes = prometheus.NewCounter(prometheus.CounterOpts{
-		Namespace:   namespace,
-		Subsystem:   exporter,
-		Name:        "scrapes_total",
-		Help:        "Total number of times PostgreSQL was scraped for metrics.",
-		ConstLabels: e.constantLabels,
-	})
-	e.error = prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace:   namespace,
-		Subsystem:   exporter,
-		Name:        "last_scrape_error",
-		Help:        "Whether the last scrape of metrics from PostgreSQL resulted in an error (1 for error, 0 for success).",
-		ConstLabels: e.constantLabels,
-	})
-	e.psqlUp = prometheus.NewGauge(prometheus.GaugeOpts{
-		Namespace:   namespace,
-		Name:        "up",
-		Help:        "Whether the last scrape of metrics from PostgreSQL was able to connect to the server (1 for yes, 0 for no).",
-		ConstLabels: e.constantLabels,
-	})
-	e.userQueriesError = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace:   namespace,
-		Subsystem:   exporter,
-		Name:        "user_queries_load_error",
-		Help:        "Whether the user queries file was loaded and parsed successfully (1 for error, 0 for success).",
-		ConstLabels: e.constantLabels,
-	}, []string{"filename", "hashsum"})
-}
-
-// Describe implements prometheus.Collector.
-func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-}
-
-// Collect implements prometheus.Collector.
-func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-	e.scrape(ch)
-
-	ch <- e.duration
-	ch <- e.totalScrapes
-	ch <- e.error
-	ch <- e.psqlUp
-	e.userQueriesError.Collect(ch)
-}
-
-func newDesc(subsystem, name, help string, labels prometheus.Labels) *prometheus.Desc {
-	return prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, subsystem, name),
-		help, nil, labels,
-	)
-}
-
-func checkPostgresVersion(db *sql.DB, server string) (semver.Version, string, error) {
-	utils.GetLogger().Debug("Querying PostgreSQL version", "server", server)
-	versionRow := db.QueryRow("SELECT version();")
-	var versionString string
-	err := versionRow.Scan(&versionString)
-	if err != nil {
-		return semver.Version{}, "", fmt.Errorf("Error scanning version string on %q: %v", server, err)
-	}
-	semanticVersion, err := parseVersion(versionString)
-	if err != nil {
-		return semver.Version{}, "", fmt.Errorf("Error parsing version string on %q: %v", server, err)
-	}
-
-	return semanticVersion, versionString, nil
-}
-
-// Check and update the exporters query maps if the version has changed.
-func (e *Exporter) checkMapVersions(ch chan<- prometheus.Metric, server *Server) error {
-	semanticVersion, versionString, err := checkPostgresVersion(server.db, server.String())
-	if err != nil {
-		return fmt.Errorf("Error fetching version string on %q: %v", server, err)
-	}
-
-	if !e.disableDefaultMetrics && semanticVersion.LT(lowestSupportedVersion) {
-		utils.GetLogger().Warn("GaussDB version is lower than our lowest supported version", "server", server, "version", semanticVersion, "lowest_supported_version", lowestSupportedVersion)
-	}
-
-	// Check if semantic version changed and recalculate maps if needed.
-	if semanticVersion.NE(server.lastMapVersion) || server.metricMap == nil {
-		utils.GetLogger().Info("Semantic version changed", "server", server, "from", server.lastMapVersion, "to", semanticVersion)
-		server.mappingMtx.Lock()
-
-		// Get Default Metrics only for master database
-		if !e.disableDefaultMetrics && server.master {
-			server.metricMap = makeDescMap(semanticVersion, server.labels, e.builtinMetricMaps)
-			server.queryOverrides = makeQueryOverrideMap(semanticVersion, queryOverrides)
-		} else {
-			server.metricMap = make(map[string]MetricMapNamespace)
-			server.queryOverrides = make(map[string]string)
-		}
-
-		server.lastMapVersion = semanticVersion
-
-		if e.userQueriesPath != "" {
-			// Clear the metric while a reload is happening
-			e.userQueriesError.Reset()
-
-			// Calculate the hashsum of the useQueries
-			userQueriesData, err := os.ReadFile(e.userQueriesPath)
-			if err != nil {
-				utils.GetLogger().Error("Failed to reload user queries", "path", e.userQueriesPath, "err", err)
-				e.userQueriesError.WithLabelValues(e.userQueriesPath, "").Set(1)
-			} else {
-				hashsumStr := fmt.Sprintf("%x", sha256.Sum256(userQueriesData))
-
-				if err := addQueries(userQueriesData, semanticVersion, server); err != nil {
-					utils.GetLogger().Error("Failed to reload user queries", "path", e.userQueriesPath, "err", err)
-					e.userQueriesError.WithLabelValues(e.userQueriesPath, hashsumStr).Set(1)
-				} else {
-					// Mark user queries as successfully loaded
-					e.userQueriesError.WithLabelValues(e.userQueriesPath, hashsumStr).Set(0)
-				}
-			}
-		}
-
-		server.mappingMtx.Unlock()
-	}
-
-	// Output the version as a special metric only for master database
-	versionDesc := prometheus.NewDesc(fmt.Sprintf("%s_%s", namespace, staticLabelName),
-		"Version string as reported by postgres", []string{"version", "short_version"}, server.labels)
-
-	if !e.disableDefaultMetrics && server.master {
-		ch <- prometheus.MustNewConstMetric(versionDesc,
-			prometheus.UntypedValue, 1, versionString, semanticVersion.String())
-	}
-	return nil
-}
-
-func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
-	defer func(begun time.Time) {
-		e.duration.Set(time.Since(begun).Seconds())
-	}(time.Now())
-
-	e.totalScrapes.Inc()
-
-	dsns := e.dsn
-	if e.autoDiscoverDatabases {
-		dsns = e.discoverDatabaseDSNs()
-	}
-
-	var errorsCount int
-	var connectionErrorsCount int
-
-	for _, dsn := range dsns {
-		if err := e.scrapeDSN(ch, dsn); err != nil {
-			errorsCount++
-
-			utils.GetLogger().Error("err", err)
-
-			/*
-			if _, ok := err.(*ErrorConnectToServer); ok {
-				fmt.Println(ok)
-				connectionErrorsCount++
-			}
-			*/
-		}
-	}
-
-	switch {
-	case connectionErrorsCount >= len(dsns):
-		e.psqlUp.Set(0)
-		//e.psqlUp.Set(1)
-	default:
-		e.psqlUp.Set(1)
-		//e.psqlUp.Set(0) // Didn't fail, can mark connection as up for this scrape.
-	}
-
-	switch errorsCount {
-	case 0:
-		e.error.Set(0)
-	default:
-		e.error.Set(1)
-	}
-}
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/gaussdb_exporter/collector"
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ColumnUsage should be one of several enum values which describe how a
+// queried row is to be converted to a Prometheus metric.
+type ColumnUsage int
+
+const (
+	// DISCARD ignores a column
+	DISCARD ColumnUsage = iota
+	// LABEL identifies a column as a label
+	LABEL ColumnUsage = iota
+	// COUNTER identifies a column as a counter
+	COUNTER ColumnUsage = iota
+	// GAUGE identifies a column as a gauge
+	GAUGE ColumnUsage = iota
+	// MAPPEDMETRIC identifies a column as a mapping of text values
+	MAPPEDMETRIC ColumnUsage = iota
+	// DURATION identifies a column as a text duration (and converted to milliseconds)
+	DURATION ColumnUsage = iota
+	// HISTOGRAM identifies a column as a histogram
+	HISTOGRAM ColumnUsage = iota
+)
+
+// UnmarshalYAML implements the yaml.Unmarshaller interface.
+func (cu *ColumnUsage) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+
+	columnUsage, err := stringToColumnUsage(value)
+	if err != nil {
+		return err
+	}
+
+	*cu = columnUsage
+	return nil
+}
+
+// MappingOptions is a copy of ColumnMapping used only for parsing
+type MappingOptions struct {
+	Usage             string             `yaml:"usage"`
+	Description       string             `yaml:"description"`
+	Mapping           map[string]float64 `yaml:"metric_mapping"` // Optional column mapping for MAPPEDMETRIC
+	SupportedVersions semver.Range       `yaml:"pg_version"`     // Semantic version ranges which are supported. Unsupported columns are not queried (internally converted to DISCARD).
+}
+
+// Mapping represents a set of MappingOptions
+type Mapping map[string]MappingOptions
+
+// Regex used to get the "short-version" from the postgres version field.
+//var versionRegex = regexp.MustCompile(`^\w+ ((\d+)(\.\d+)?(\.\d+)?)`)
+//KingbaseES V008R006C008B0014
+//var versionRegex = regexp.MustCompile(`^KingbaseES ([0-9a-zA-Z]*)`)
+var versionRegex = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+var lowestSupportedVersion = semver.MustParse("5.0.0")
+
+// Parses the version of postgres into the short version string we can use to
+// match behaviors.
+func parseVersion(versionString string) (semver.Version, error) {
+	submatches := versionRegex.FindStringSubmatch(versionString)
+	if len(submatches) > 2 {
+		major, _ := strconv.Atoi(submatches[1])
+		minor, _ := strconv.Atoi(submatches[2])
+		patch, _ := strconv.Atoi(submatches[3])
+		version := fmt.Sprintf("%d.%d.%d", major, minor, patch)
+		return semver.ParseTolerant(version)
+	}
+	return semver.Version{},
+		errors.New(fmt.Sprintln("Could not find a gaussdb version in string:", versionString))
+}
+
+// ColumnMapping is the user-friendly representation of a prometheus descriptor map
+type ColumnMapping struct {
+	usage             ColumnUsage        `yaml:"usage"`
+	description       string             `yaml:"description"`
+	mapping           map[string]float64 `yaml:"metric_mapping"` // Optional column mapping for MAPPEDMETRIC
+	supportedVersions semver.Range       `yaml:"pg_version"`     // Semantic version ranges which are supported. Unsupported columns are not queried (internally converted to DISCARD).
+}
+
+// UnmarshalYAML implements yaml.Unmarshaller
+func (cm *ColumnMapping) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain ColumnMapping
+	return unmarshal((*plain)(cm))
+}
+
+// intermediateMetricMap holds the partially loaded metric map parsing.
+// This is mainly so we can parse cacheSeconds around.
+type intermediateMetricMap struct {
+	columnMappings map[string]ColumnMapping
+	master         bool
+	cacheSeconds   uint64
+}
+
+// MetricMapNamespace groups metric maps under a shared set of labels.
+type MetricMapNamespace struct {
+	labels         []string             // Label names for this namespace
+	columnMappings map[string]MetricMap // Column mappings in this namespace
+	master         bool                 // Call query only for master database
+	cacheSeconds   uint64               // Number of seconds this metric namespace can be cached. 0 disables.
+}
+
+// MetricMap stores the prometheus metric description which a given column will
+// be mapped to by the collector
+type MetricMap struct {
+	discard    bool                              // Should metric be discarded during mapping?
+	histogram  bool                              // Should metric be treated as a histogram?
+	vtype      prometheus.ValueType              // Prometheus valuetype
+	desc       *prometheus.Desc                  // Prometheus descriptor
+	conversion func(interface{}) (float64, bool) // Conversion function to turn PG result into float64
+}
+
+// ErrorConnectToServer is a connection to PgSQL server error
+type ErrorConnectToServer struct {
+	Msg string
+}
+
+// Error returns error
+func (e *ErrorConnectToServer) Error() string {
+	return e.Msg
+}
+
+// TODO: revisit this with the semver system
+func dumpMaps() {
+	// TODO: make this function part of the exporter
+	for name, cmap := range builtinMetricMaps {
+		query, ok := queryOverrides[name]
+		if !ok {
+			fmt.Println(name)
+		} else {
+			for _, queryOverride := range query {
+				fmt.Println(name, queryOverride.versionRange, queryOverride.query)
+			}
+		}
+
+		for column, details := range cmap.columnMappings {
+			fmt.Printf("  %-40s %v\n", column, details)
+		}
+		fmt.Println()
+	}
+}
+
+var builtinMetricMaps = map[string]intermediateMetricMap{
+	"pg_stat_database_conflicts": {
+		map[string]ColumnMapping{
+			"datid":            {LABEL, "OID of a database", nil, nil},
+			"datname":          {LABEL, "Name of this database", nil, nil},
+			"confl_tablespace": {COUNTER, "Number of queries in this database that have been canceled due to dropped tablespaces", nil, nil},
+			"confl_lock":       {COUNTER, "Number of queries in this database that have been canceled due to lock timeouts", nil, nil},
+			"confl_snapshot":   {COUNTER, "Number of queries in this database that have been canceled due to old snapshots", nil, nil},
+			"confl_bufferpin":  {COUNTER, "Number of queries in this database that have been canceled due to pinned buffers", nil, nil},
+			"confl_deadlock":   {COUNTER, "Number of queries in this database that have been canceled due to deadlocks", nil, nil},
+		},
+		true,
+		0,
+	},
+	"pg_stat_replication": {
+		map[string]ColumnMapping{
+			"procpid":          {DISCARD, "Process ID of a WAL sender process", nil, semver.MustParseRange("<9.2.0")},
+			"pid":              {DISCARD, "Process ID of a WAL sender process", nil, semver.MustParseRange(">=9.2.0")},
+			"usesysid":         {DISCARD, "OID of the user logged into this WAL sender process", nil, nil},
+			"usename":          {DISCARD, "Name of the user logged into this WAL sender process", nil, nil},
+			"application_name": {LABEL, "Name of the application that is connected to this WAL sender", nil, nil},
+			"client_addr":      {LABEL, "IP address of the client connected to this WAL sender. If this field is null, it indicates that the client is connected via a Unix socket on the server machine.", nil, nil},
+			"client_hostname":  {DISCARD, "Host name of the connected client, as reported by a reverse DNS lookup of client_addr. This field will only be non-null for IP connections, and only when log_hostname is enabled.", nil, nil},
+			"client_port":      {DISCARD, "TCP port number that the client is using for communication with this WAL sender, or -1 if a Unix socket is used", nil, nil},
+			"backend_start": {DISCARD, "with time zone	Time when this process was started, i.e., when the client connected to this WAL sender", nil, nil},
+			"backend_xmin":             {DISCARD, "The current backend's xmin horizon.", nil, nil},
+			"state":                    {LABEL, "Current WAL sender state", nil, nil},
+			"sent_location":            {DISCARD, "Last transaction log position sent on this connection", nil, semver.MustParseRange("<10.0.0")},
+			"write_location":           {DISCARD, "Last transaction log position written to disk by this standby server", nil, semver.MustParseRange("<10.0.0")},
+			"flush_location":           {DISCARD, "Last transaction log position flushed to disk by this standby server", nil, semver.MustParseRange("<10.0.0")},
+			"replay_location":          {DISCARD, "Last transaction log position replayed into the database on this standby server", nil, semver.MustParseRange("<10.0.0")},
+			"sent_lsn":                 {DISCARD, "Last transaction log position sent on this connection", nil, semver.MustParseRange(">=10.0.0")},
+			"write_lsn":                {DISCARD, "Last transaction log position written to disk by this standby server", nil, semver.MustParseRange(">=10.0.0")},
+			"flush_lsn":                {DISCARD, "Last transaction log position flushed to disk by this standby server", nil, semver.MustParseRange(">=10.0.0")},
+			"replay_lsn":               {DISCARD, "Last transaction log position replayed into the database on this standby server", nil, semver.MustParseRange(">=10.0.0")},
+			"sync_priority":            {DISCARD, "Priority of this standby server for being chosen as the synchronous standby", nil, nil},
+			"sync_state":               {DISCARD, "Synchronous state of this standby server", nil, nil},
+			"slot_name":                {LABEL, "A unique, cluster-wide identifier for the replication slot", nil, semver.MustParseRange(">=9.2.0")},
+			"plugin":                   {DISCARD, "The base name of the shared object containing the output plugin this logical slot is using, or null for physical slots", nil, nil},
+			"slot_type":                {DISCARD, "The slot type - physical or logical", nil, nil},
+			"datoid":                   {DISCARD, "The OID of the database this slot is associated with, or null. Only logical slots have an associated database", nil, nil},
+			"database":                 {DISCARD, "The name of the database this slot is associated with, or null. Only logical slots have an associated database", nil, nil},
+			"active":                   {DISCARD, "True if this slot is currently actively being used", nil, nil},
+			"active_pid":               {DISCARD, "Process ID of a WAL sender process", nil, nil},
+			"xmin":                     {DISCARD, "The oldest transaction that this slot needs the database to retain. VACUUM cannot remove tuples deleted by any later transaction", nil, nil},
+			"catalog_xmin":             {DISCARD, "The oldest transaction affecting the system catalogs that this slot needs the database to retain. VACUUM cannot remove catalog tuples deleted by any later transaction", nil, nil},
+			"restart_lsn":              {DISCARD, "The address (LSN) of oldest WAL which still might be required by the consumer of this slot and thus won't be automatically removed during checkpoints", nil, nil},
+			"pg_current_xlog_location": {DISCARD, "pg_current_xlog_location", nil, nil},
+			"pg_current_wal_lsn":       {DISCARD, "pg_current_xlog_location", nil, semver.MustParseRange(">=10.0.0")},
+			"pg_current_wal_lsn_bytes": {GAUGE, "WAL position in bytes", nil, semver.MustParseRange(">=10.0.0")},
+			"pg_xlog_location_diff":    {GAUGE, "Lag in bytes between master and slave", nil, semver.MustParseRange(">=9.2.0 <10.0.0")},
+			"pg_wal_lsn_diff":          {GAUGE, "Lag in bytes between master and slave", nil, semver.MustParseRange(">=10.0.0")},
+			"confirmed_flush_lsn":      {DISCARD, "LSN position a consumer of a slot has confirmed flushing the data received", nil, nil},
+			"write_lag":                {DISCARD, "Time elapsed between flushing recent WAL locally and receiving notification that this standby server has written it (but not yet flushed it or applied it). This can be used to gauge the delay that synchronous_commit level remote_write incurred while committing if this server was configured as a synchronous standby.", nil, semver.MustParseRange(">=10.0.0")},
+			"flush_lag":                {DISCARD, "Time elapsed between flushing recent WAL locally and receiving notification that this standby server has written and flushed it (but not yet applied it). This can be used to gauge the delay that synchronous_commit level remote_flush incurred while committing if this server was configured as a synchronous standby.", nil, semver.MustParseRange(">=10.0.0")},
+			"replay_lag":               {DISCARD, "Time elapsed between flushing recent WAL locally and receiving notification that this standby server has written, flushed and applied it. This can be used to gauge the delay that synchronous_commit level remote_apply incurred while committing if this server was configured as a synchronous standby.", nil, semver.MustParseRange(">=10.0.0")},
+		},
+		true,
+		0,
+	},
+	"pg_replication_slots": {
+		map[string]ColumnMapping{
+			"slot_name":       {LABEL, "Name of the replication slot", nil, nil},
+			"database":        {LABEL, "Name of the database", nil, nil},
+			"active":          {GAUGE, "Flag indicating if the slot is active", nil, nil},
+			"pg_wal_lsn_diff": {GAUGE, "Replication lag in bytes", nil, nil},
+		},
+		true,
+		0,
+	},
+	"pg_stat_archiver": {
+		map[string]ColumnMapping{
+			"archived_count":     {COUNTER, "Number of WAL files that have been successfully archived", nil, nil},
+			"last_archived_wal":  {DISCARD, "Name of the last WAL file successfully archived", nil, nil},
+			"last_archived_time": {DISCARD, "Time of the last successful archive operation", nil, nil},
+			"failed_count":       {COUNTER, "Number of failed attempts for archiving WAL files", nil, nil},
+			"last_failed_wal":    {DISCARD, "Name of the WAL file of the last failed archival operation", nil, nil},
+			"last_failed_time":   {DISCARD, "Time of the last failed archival operation", nil, nil},
+			"stats_reset":        {DISCARD, "Time at which these statistics were last reset", nil, nil},
+			"last_archive_age":   {GAUGE, "Time in seconds since last WAL segment was successfully archived", nil, nil},
+		},
+		true,
+		0,
+	},
+	"pg_stat_activity": {
+		map[string]ColumnMapping{
+			"datname":          {LABEL, "Name of this database", nil, nil},
+			"state":            {LABEL, "connection state", nil, semver.MustParseRange(">=9.2.0")},
+			"usename":          {LABEL, "connection usename", nil, nil},
+			"application_name": {LABEL, "connection application_name", nil, nil},
+			"count":            {GAUGE, "number of connections in this state", nil, nil},
+			"max_tx_duration":  {GAUGE, "max duration in seconds any active transaction has been running", nil, nil},
+		},
+		true,
+		0,
+	},
+}
+
+// Turn the MetricMap column mapping into a prometheus descriptor mapping.
+func makeDescMap(pgVersion semver.Version, serverLabels prometheus.Labels, metricMaps map[string]intermediateMetricMap, logger *utils.Logger) map[string]MetricMapNamespace {
+	var metricMap = make(map[string]MetricMapNamespace)
+
+	for namespace, intermediateMappings := range metricMaps {
+		thisMap := make(map[string]MetricMap)
+
+		namespace = strings.Replace(namespace, "gs", *metricPrefix, 1)
+
+		// Get the constant labels
+		var variableLabels []string
+		for columnName, columnMapping := range intermediateMappings.columnMappings {
+			if columnMapping.usage == LABEL {
+				variableLabels = append(variableLabels, columnName)
+			}
+		}
+
+		for columnName, columnMapping := range intermediateMappings.columnMappings {
+			// Check column version compatibility for the current map
+			// Force to discard if not compatible.
+			if columnMapping.supportedVersions != nil {
+				if !columnMapping.supportedVersions(pgVersion) {
+					// It's very useful to be able to see what columns are being
+					// rejected.
+					logger.Debug("Column is being forced to discard due to version incompatibility", "column", columnName)
+					thisMap[columnName] = MetricMap{
+						discard: true,
+						conversion: func(_ interface{}) (float64, bool) {
+							return math.NaN(), true
+						},
+					}
+					continue
+				}
+			}
+
+			// Determine how to convert the column based on its usage.
+			// nolint: dupl
+			switch columnMapping.usage {
+			case DISCARD, LABEL:
+				thisMap[columnName] = MetricMap{
+					discard: true,
+					conversion: func(_ interface{}) (float64, bool) {
+						return math.NaN(), true
+					},
+				}
+			case COUNTER:
+				thisMap[columnName] = MetricMap{
+					vtype: prometheus.CounterValue,
+					desc:  prometheus.NewDesc(fmt.Sprintf("%s_%s", namespace, columnName), columnMapping.description, variableLabels, serverLabels),
+					conversion: func(in interface{}) (float64, bool) {
+						return dbToFloat64(in)
+					},
+				}
+			case GAUGE:
+				thisMap[columnName] = MetricMap{
+					vtype: prometheus.GaugeValue,
+					desc:  prometheus.NewDesc(fmt.Sprintf("%s_%s", namespace, columnName), columnMapping.description, variableLabels, serverLabels),
+					conversion: func(in interface{}) (float64, bool) {
+						return dbToFloat64(in)
+					},
+				}
+			case HISTOGRAM:
+				thisMap[columnName] = MetricMap{
+					histogram: true,
+					vtype:     prometheus.UntypedValue,
+					desc:      prometheus.NewDesc(fmt.Sprintf("%s_%s", namespace, columnName), columnMapping.description, variableLabels, serverLabels),
+					conversion: func(in interface{}) (float64, bool) {
+						return dbToFloat64(in)
+					},
+				}
+				thisMap[columnName+"_bucket"] = MetricMap{
+					histogram: true,
+					discard:   true,
+				}
+				thisMap[columnName+"_sum"] = MetricMap{
+					histogram: true,
+					discard:   true,
+				}
+				thisMap[columnName+"_count"] = MetricMap{
+					histogram: true,
+					discard:   true,
+				}
+			case MAPPEDMETRIC:
+				thisMap[columnName] = MetricMap{
+					vtype: prometheus.GaugeValue,
+					desc:  prometheus.NewDesc(fmt.Sprintf("%s_%s", namespace, columnName), columnMapping.description, variableLabels, serverLabels),
+					conversion: func(in interface{}) (float64, bool) {
+						text, ok := in.(string)
+						if !ok {
+							return math.NaN(), false
+						}
+
+						val, ok := columnMapping.mapping[text]
+						if !ok {
+							return math.NaN(), false
+						}
+						return val, true
+					},
+				}
+			case DURATION:
+				thisMap[columnName] = MetricMap{
+					vtype: prometheus.GaugeValue,
+					desc:  prometheus.NewDesc(fmt.Sprintf("%s_%s_milliseconds", namespace, columnName), columnMapping.description, variableLabels, serverLabels),
+					conversion: func(in interface{}) (float64, bool) {
+						var durationString string
+						switch t := in.(type) {
+						case []byte:
+							durationString = string(t)
+						case string:
+							durationString = t
+						default:
+							logger.Error("Duration conversion metric was not a string", "column", columnName)
+							return math.NaN(), false
+						}
+
+						if durationString == "-1" {
+							return math.NaN(), false
+						}
+
+						d, err := time.ParseDuration(durationString)
+						if err != nil {
+							logger.Error("Failed converting result to metric", "column", columnName, "in", in, "err", err)
+							return math.NaN(), false
+						}
+						return float64(d / time.Millisecond), true
+					},
+				}
+			}
+		}
+
+		metricMap[namespace] = MetricMapNamespace{variableLabels, thisMap, intermediateMappings.master, intermediateMappings.cacheSeconds}
+	}
+
+	return metricMap
+}
+
+type cachedMetrics struct {
+	metrics    []prometheus.Metric
+	lastScrape time.Time
+}
+
+// Exporter collects Postgres metrics. It implements prometheus.Collector.
+type Exporter struct {
+	// Holds a reference to the build in column mappings. Currently this is for testing purposes
+	// only, since it just points to the global.
+	builtinMetricMaps map[string]intermediateMetricMap
+
+	disableDefaultMetrics, disableSettingsMetrics, autoDiscoverDatabases bool
+
+	databases        collector.DatabaseFilter
+	dsn              []string
+	userQueriesPath  string
+	constantLabels   prometheus.Labels
+	duration         prometheus.Gauge
+	error            prometheus.Gauge
+	psqlUp           prometheus.Gauge
+	userQueriesError *prometheus.GaugeVec
+	totalScrapes     prometheus.Counter
+	logger           *utils.Logger
+	// ctx bounds the queries of a scrape, see WithContext.
+	ctx context.Context
+	// shared are long-lived servers for some of the DSNs, see WithServer.
+	shared map[string]*Server
+
+	// servers are used to allow re-using the DB connection between scrapes.
+	// servers contains metrics map and query overrides.
+	servers *Servers
+}
+
+// ExporterOpt configures Exporter.
+type ExporterOpt func(*Exporter)
+
+// DisableDefaultMetrics configures default metrics export.
+func DisableDefaultMetrics(b bool) ExporterOpt {
+	return func(e *Exporter) {
+		e.disableDefaultMetrics = b
+	}
+}
+
+// DisableSettingsMetrics configures pg_settings export.
+func DisableSettingsMetrics(b bool) ExporterOpt {
+	return func(e *Exporter) {
+		e.disableSettingsMetrics = b
+	}
+}
+
+// AutoDiscoverDatabases allows scraping all databases on a database server.
+func AutoDiscoverDatabases(b bool) ExporterOpt {
+	return func(e *Exporter) {
+		e.autoDiscoverDatabases = b
+	}
+}
+
+// ExcludeDatabases allows to filter out result from AutoDiscoverDatabases
+// and the per-database metrics
+func ExcludeDatabases(s []string) ExporterOpt {
+	return func(e *Exporter) {
+		e.databases.Exclude = s
+	}
+}
+
+// IncludeDatabases allows to filter result from AutoDiscoverDatabases
+// and the per-database metrics
+func IncludeDatabases(s string) ExporterOpt {
+	return func(e *Exporter) {
+		if len(s) > 0 {
+			e.databases.Include = strings.Split(s, ",")
+		}
+	}
+}
+
+// WithDatabases sets both the databases excluded and the ones included.
+func WithDatabases(f collector.DatabaseFilter) ExporterOpt {
+	return func(e *Exporter) {
+		e.databases = f
+	}
+}
+
+// WithUserQueriesPath configures user's queries path.
+func WithUserQueriesPath(p string) ExporterOpt {
+	return func(e *Exporter) {
+		e.userQueriesPath = p
+	}
+}
+
+// WithLogger configures the logger.
+func WithLogger(logger *utils.Logger) ExporterOpt {
+	return func(e *Exporter) {
+		e.logger = logger
+	}
+}
+
+// WithContext sets the context the queries of a scrape run under, so that they
+// are cancelled when the scrape request is.
+func WithContext(ctx context.Context) ExporterOpt {
+	return func(e *Exporter) {
+		e.ctx = ctx
+	}
+}
+
+// WithServer makes the exporter scrape dsn through server, a server kept by
+// the connection manager, instead of setting up a server of its own. Its
+// version and metric maps then carry over from one scrape to the next.
+func WithServer(dsn string, server *Server) ExporterOpt {
+	return func(e *Exporter) {
+		if e.shared == nil {
+			e.shared = make(map[string]*Server)
+		}
+		e.shared[dsn] = server
+	}
+}
+
+// WithConstantLabels configures constant labels.
+func WithConstantLabels(s string) ExporterOpt {
+	return func(e *Exporter) {
+		e.constantLabels = parseConstLabels(s)
+	}
+}
+
+func parseConstLabels(s string) prometheus.Labels {
+	labels := make(prometheus.Labels)
+
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return labels
+	}
+
+	parts := strings.Split(s, ",")
+	for _, p := range parts {
+		keyValue := strings.Split(strings.TrimSpace(p), "=")
+		if len(keyValue) != 2 {
+			utils.GetLogger().Error(`Wrong constant labels format, should be "key=value"`, "input", p)
+			continue
+		}
+		key := strings.TrimSpace(keyValue[0])
+		value := strings.TrimSpace(keyValue[1])
+		if key == "" || value == "" {
+			continue
+		}
+		labels[key] = value
+	}
+
+	return labels
+}
+
+// NewExporter returns a new PostgreSQL exporter for the provided DSN.
+func NewExporter(dsn []string, opts ...ExporterOpt) *Exporter {
+	e := &Exporter{
+		dsn:               dsn,
+		builtinMetricMaps: builtinMetricMaps,
+		logger:            utils.GetLogger(),
+		ctx:               context.Background(),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	e.setupInternalMetrics()
+	e.servers = NewServers(ServerWithLabels(e.constantLabels), ServerWithLogger(e.logger))
+	e.servers.shared = e.shared
+
+	return e
+}
+
+func (e *Exporter) setupInternalMetrics() {
+	e.duration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   exporter,
+		Name:        "last_scrape_duration_seconds",
+		Help:        "Duration of the last scrape of metrics from PostgreSQL.",
+		ConstLabels: e.constantLabels,
+	})
+	e.totalScrapes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   namespace,
+		Subsystem:   exporter,
+		Name:        "scrapes_total",
+		Help:        "Total number of times PostgreSQL was scraped for metrics.",
+		ConstLabels: e.constantLabels,
+	})
+	e.error = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   exporter,
+		Name:        "last_scrape_error",
+		Help:        "Whether the last scrape of metrics from PostgreSQL resulted in an error (1 for error, 0 for success).",
+		ConstLabels: e.constantLabels,
+	})
+	e.psqlUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "up",
+		Help:        "Whether the last scrape of metrics from PostgreSQL was able to connect to the server (1 for yes, 0 for no).",
+		ConstLabels: e.constantLabels,
+	})
+	e.userQueriesError = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   exporter,
+		Name:        "user_queries_load_error",
+		Help:        "Whether the user queries file was loaded and parsed successfully (1 for error, 0 for success).",
+		ConstLabels: e.constantLabels,
+	}, []string{"filename", "hashsum"})
+}
+
+// Describe implements prometheus.Collector.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect implements prometheus.Collector.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.scrape(ch)
+
+	ch <- e.duration
+	ch <- e.totalScrapes
+	ch <- e.error
+	ch <- e.psqlUp
+	e.userQueriesError.Collect(ch)
+}
+
+func newDesc(subsystem, name, help string, labels prometheus.Labels) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, name),
+		help, nil, labels,
+	)
+}
+
+func checkPostgresVersion(ctx context.Context, db *sql.DB, server string, logger *utils.Logger) (semver.Version, string, error) {
+	logger.Debug("Querying PostgreSQL version")
+	versionRow := db.QueryRowContext(ctx, "SELECT version();")
+	var versionString string
+	err := versionRow.Scan(&versionString)
+	if err != nil {
+		return semver.Version{}, "", fmt.Errorf("Error scanning version string on %q: %v", server, err)
+	}
+	semanticVersion, err := parseVersion(versionString)
+	if err != nil {
+		return semver.Version{}, "", fmt.Errorf("Error parsing version string on %q: %v", server, err)
+	}
+
+	return semanticVersion, versionString, nil
+}
+
+// Check and update the exporters query maps if the version has changed.
+func (e *Exporter) checkMapVersions(ctx context.Context, ch chan<- prometheus.Metric, server *Server) error {
+	semanticVersion, versionString, err := server.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("Error fetching version string on %q: %v", server, err)
+	}
+
+	if !e.disableDefaultMetrics && semanticVersion.LT(lowestSupportedVersion) {
+		server.logger.Warn("GaussDB version is lower than our lowest supported version", "version", semanticVersion, "lowest_supported_version", lowestSupportedVersion)
+	}
+
+	// Check if semantic version changed and recalculate maps if needed.
+	server.mappingMtx.Lock()
+	if semanticVersion.NE(server.lastMapVersion) || server.metricMap == nil {
+		server.logger.Info("Semantic version changed", "from", server.lastMapVersion, "to", semanticVersion)
+
+		// Get Default Metrics only for master database
+		if !e.disableDefaultMetrics && server.master {
+			server.metricMap = makeDescMap(semanticVersion, server.labels, e.builtinMetricMaps, server.logger)
+			server.queryOverrides = makeQueryOverrideMap(semanticVersion, queryOverrides, server.logger)
+		} else {
+			server.metricMap = make(map[string]MetricMapNamespace)
+			server.queryOverrides = make(map[string]string)
+		}
+
+		server.lastMapVersion = semanticVersion
+
+		if e.userQueriesPath != "" {
+			// Clear the metric while a reload is happening
+			e.userQueriesError.Reset()
+
+			// Calculate the hashsum of the useQueries
+			userQueriesData, err := os.ReadFile(e.userQueriesPath)
+			if err != nil {
+				server.logger.Error("Failed to reload user queries", "path", e.userQueriesPath, "err", err)
+				e.userQueriesError.WithLabelValues(e.userQueriesPath, "").Set(1)
+			} else {
+				hashsumStr := fmt.Sprintf("%x", sha256.Sum256(userQueriesData))
+
+				if err := addQueries(userQueriesData, semanticVersion, server); err != nil {
+					server.logger.Error("Failed to reload user queries", "path", e.userQueriesPath, "err", err)
+					e.userQueriesError.WithLabelValues(e.userQueriesPath, hashsumStr).Set(1)
+				} else {
+					// Mark user queries as successfully loaded
+					e.userQueriesError.WithLabelValues(e.userQueriesPath, hashsumStr).Set(0)
+				}
+			}
+		}
+	}
+	server.mappingMtx.Unlock()
+
+	// Output the version as a special metric only for master database
+	versionDesc := prometheus.NewDesc(fmt.Sprintf("%s_%s", namespace, staticLabelName),
+		"Version string as reported by postgres", []string{"version", "short_version"}, server.labels)
+
+	if !e.disableDefaultMetrics && server.master {
+		ch <- prometheus.MustNewConstMetric(versionDesc,
+			prometheus.UntypedValue, 1, versionString, semanticVersion.String())
+	}
+	return nil
+}
+
+func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
+	defer func(begun time.Time) {
+		e.duration.Set(time.Since(begun).Seconds())
+	}(time.Now())
+
+	e.totalScrapes.Inc()
+
+	dsns := e.dsn
+	if e.autoDiscoverDatabases {
+		dsns = e.discoverDatabaseDSNs(e.ctx)
+	}
+
+	var errorsCount int
+	var connectionErrorsCount int
+
+	for _, dsn := range dsns {
+		if err := e.scrapeDSN(e.ctx, ch, dsn); err != nil {
+			errorsCount++
+
+			e.logger.Error("Error scraping dsn", "err", err)
+
+			var connErr *ErrorConnectToServer
+			if errors.As(err, &connErr) {
+				connectionErrorsCount++
+			}
+		}
+	}
+
+	switch {
+	case connectionErrorsCount >= len(dsns):
+		e.psqlUp.Set(0)
+	default:
+		e.psqlUp.Set(1) // Didn't fail, can mark connection as up for this scrape.
+	}
+
+	switch errorsCount {
+	case 0:
+		e.error.Set(0)
+	default:
+		e.error.Set(1)
+	}
+}
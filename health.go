@@ -0,0 +1,116 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons a connection to an instance failed, as counted by
+// connectFailures.
+const (
+	reasonAuth    = "auth"
+	reasonNetwork = "network"
+	reasonTimeout = "timeout"
+	reasonOther   = "other"
+)
+
+var (
+	connectDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: exporter,
+		Name:      "instance_connect_duration_seconds",
+		Help:      "Time the last scrape of the instance took to get a working connection, whether it succeeded or not.",
+	}, []string{"instance_id"})
+
+	connectFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: exporter,
+		Name:      "instance_connect_failures_total",
+		Help:      "Total number of scrapes that couldn't connect to the instance, by whether authentication failed, the network did, the scrape ran out of time or something else went wrong.",
+	}, []string{"instance_id", "reason"})
+
+	lastSuccessfulScrape = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: exporter,
+		Name:      "instance_last_successful_scrape_timestamp_seconds",
+		Help:      "When a scrape last connected to the instance.",
+	}, []string{"instance_id"})
+
+	consecutiveFailures = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: exporter,
+		Name:      "instance_consecutive_failed_scrapes",
+		Help:      "Number of scrapes in a row that couldn't connect to the instance.",
+	}, []string{"instance_id"})
+)
+
+func init() {
+	exporterRegistry.MustRegister(connectDuration, connectFailures, lastSuccessfulScrape, consecutiveFailures)
+}
+
+// recordConnect records in the health metrics of instanceId how getting a
+// connection for a scrape went, and how long it took.
+func recordConnect(instanceId string, took time.Duration, err error) {
+	connectDuration.WithLabelValues(instanceId).Set(took.Seconds())
+	if err != nil {
+		connectFailures.WithLabelValues(instanceId, connectFailureReason(err)).Inc()
+		consecutiveFailures.WithLabelValues(instanceId).Inc()
+		return
+	}
+	lastSuccessfulScrape.WithLabelValues(instanceId).SetToCurrentTime()
+	consecutiveFailures.WithLabelValues(instanceId).Set(0)
+}
+
+// connectFailureReason tells authentication failures apart from network
+// ones. Errors reported by the server carry an SQLSTATE, in class 28 for
+// authentication; drivers that don't expose it are matched on their message.
+func connectFailureReason(err error) string {
+	var sqlErr interface{ SQLState() string }
+	if errors.As(err, &sqlErr) {
+		if strings.HasPrefix(sqlErr.SQLState(), "28") {
+			return reasonAuth
+		}
+		return reasonOther
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return reasonTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return reasonTimeout
+		}
+		return reasonNetwork
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return reasonNetwork
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"password", "authentication", "login denied", "pg_hba.conf"} {
+		if strings.Contains(msg, s) {
+			return reasonAuth
+		}
+	}
+	return reasonOther
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "pq: " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestConnectFailureReason(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{sqlStateError("28P01"), reasonAuth},
+		{fmt.Errorf("ping: %w", sqlStateError("28000")), reasonAuth},
+		{sqlStateError("57P03"), reasonOther},
+		{errors.New("pq: Invalid username/password,login denied."), reasonAuth},
+		{errors.New(`pq: password authentication failed for user "gaussdb"`), reasonAuth},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, reasonNetwork},
+		{&net.DNSError{Err: "no such host", Name: "db.example"}, reasonNetwork},
+		{&net.DNSError{Err: "i/o timeout", Name: "db.example", IsTimeout: true}, reasonTimeout},
+		{io.EOF, reasonNetwork},
+		{context.DeadlineExceeded, reasonTimeout},
+		{errors.New("missing \"=\" after \"host\" in connection info string"), reasonOther},
+	} {
+		if got := connectFailureReason(tc.err); got != tc.want {
+			t.Errorf("connectFailureReason(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestInstanceGatherer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	failures := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "failures"}, []string{"instance_id"})
+	failures.WithLabelValues("a").Set(1)
+	failures.WithLabelValues("b").Set(2)
+	reloads := prometheus.NewCounter(prometheus.CounterOpts{Name: "reloads_total"})
+	registry.MustRegister(failures, reloads)
+
+	families, err := instanceGatherer(registry, "b").Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 1 || families[0].GetName() != "failures" {
+		t.Fatalf("expected only the failures family, got %v", families)
+	}
+	if metrics := families[0].GetMetric(); len(metrics) != 1 || metrics[0].GetGauge().GetValue() != 2 {
+		t.Errorf("expected only the series of instance b, got %v", metrics)
+	}
+}
+
+func TestAddLabels(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	sessions := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "sessions"}, []string{"datname"})
+	sessions.WithLabelValues("school").Set(1)
+	registry.MustRegister(sessions)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addLabels(families, prometheus.Labels{"env": "prod", "datname": "x"}, utils.GetLogger())
+
+	got := make(map[string]string)
+	for _, l := range families[0].GetMetric()[0].GetLabel() {
+		got[l.GetName()] = l.GetValue()
+	}
+	if len(got) != 2 || got["env"] != "prod" || got["datname"] != "school" {
+		t.Errorf("expected env added and datname kept, got %v", got)
+	}
+}
@@ -1,111 +1,176 @@
-// Copyright 2021 The Prometheus Authors
-// Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this file except in compliance with the License.
-// You may obtain a copy of the License at
-//
-// http://www.apache.org/licenses/LICENSE-2.0
-//
-// Unless required by applicable law or agreed to in writing, software
-// distributed under the License is distributed on an "AS IS" BASIS,
-// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-// See the License for the specific language governing permissions and
-// limitations under the License.
-
-package main
-
-import (
-	"fmt"
-	"log"
-	"os"
-	"net/http"
-
-	"github.com/alecthomas/kingpin/v2"
-	//"github.com/prometheus-community/gaussdb_exporter/config"
-	"github.com/prometheus-community/gaussdb_exporter/utils"
-	"github.com/prometheus/common/promlog"
-	"github.com/prometheus/common/promlog/flag"
-	"github.com/prometheus/common/version"
-
-	"myencrypt"
-	"config"
-)
-
-var (
-	cfgHandler = config.Handler{}
-	logger = log.New(os.Stdout,"",log.Lshortfile | log.Ldate | log.Ltime)
-
-	listenAddress          = kingpin.Flag("web.listen-address", "sever listen port.").Default("9334").Int16()
-	metricsPath            = kingpin.Flag("web.metrics-path", "Path under which to expose metrics.").Default("/metrics").Envar("PG_EXPORTER_WEB_TELEMETRY_PATH").String()
-	disableDefaultMetrics  = kingpin.Flag("disable-default-metrics", "Do not include default metrics.").Default("false").Envar("PG_EXPORTER_DISABLE_DEFAULT_METRICS").Bool()
-	disableSettingsMetrics = kingpin.Flag("disable-settings-metrics", "Do not include pg_settings metrics.").Default("false").Envar("PG_EXPORTER_DISABLE_SETTINGS_METRICS").Bool()
-	metricPrefix           = kingpin.Flag("metric-prefix", "A metric prefix can be used to have non-default (not \"gs\") prefixes for each of the metrics").Default("gs").Envar("PG_EXPORTER_METRIC_PREFIX").String()
-)
-
-// Metric name parts.
-const (
-	// Namespace for all metrics.
-	namespace = "gs"
-	// Subsystems.
-	exporter = "exporter"
-	// The name of the exporter.
-	exporterName = "gaussdb_exporter"
-	// Metric label used for static string data thats handy to send to Prometheus
-	// e.g. version
-	staticLabelName = "static"
-	// Metric label used for server identification.
-	serverLabelName = "server"
-)
-
-func main() {
-
-	var target_info myencrypt.Target_info
-
-	kingpin.Version(version.Print(exporterName))
-	promlogConfig := &promlog.Config{}
-	flag.AddFlags(kingpin.CommandLine, promlogConfig)
-	kingpin.HelpFlag.Short('h')
-	kingpin.Parse()
-
-	if fi,err := os.Stat("HISTORY");err == nil || os.IsExist(err) {
-		logger.Println("监测到HISTORY文件，执行新模式")
-
-		if fi.Size() < 1 {
-			logger.Println("HISTORY文件是空的，部署有问题，无法继续")
-			os.Exit(1)
-		}
-
-		//从一体化运维平台接受参数并且生成配置文件
-		target_info = myencrypt.CheckInstall(fmt.Sprintf(":%d",*listenAddress))
-		config_file := cfgHandler.InitFromUrl(target_info.InstanceId,target_info.Excludedbs,target_info.Hosts,target_info.Port,target_info.DB,target_info.User,target_info.Password,"0",1)
-		cfgHandler.WriteConfigFile(config_file)
-		if err := cfgHandler.ReloadConfig(); err != nil {
-			logger.Println(err)
-			utils.GetLogger().Warn("Error loading config", "err", err)
-		}
-
-
-	} else {
-		logger.Println("未监测到部署文件，不做任何改动，当前行为与官方包一致")
-		if err := cfgHandler.ReloadConfig(); err != nil {
-			logger.Println(err)
-			utils.GetLogger().Warn("Error loading config", "err", err)
-		}
-	}
-
-	http.HandleFunc(*metricsPath, handleProbe(target_info.InstanceId))
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, `<html>
-		<head><title>HuaWei GaussDB Exporter</title></head>
-		<body>
-		<h2>Welcome to use HuaWei GaussDB Exporter</h2>
-		</body>
-		</html>`)
-	})
-
-	utils.GetLogger().Info("服务已经启动", "server listen port", *listenAddress)
-	err := http.ListenAndServe(fmt.Sprintf(":%d", *listenAddress), nil)
-	if err != nil {
-		logger.Println("ListenAndServe err:", err)
-		utils.GetLogger().Error("msg:", "无法启动web容器服务", "error:", err)
-	}
-}
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/alecthomas/kingpin/v2"
+	//"github.com/prometheus-community/gaussdb_exporter/config"
+	"github.com/prometheus-community/gaussdb_exporter/collector"
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/promlog"
+	"github.com/prometheus/common/promlog/flag"
+	"github.com/prometheus/common/version"
+	"github.com/prometheus/exporter-toolkit/web"
+
+	"myencrypt"
+	"config"
+)
+
+var (
+	cfgHandler = config.Handler{}
+	promlogConfig = &promlog.Config{}
+
+	listenAddress          = kingpin.Flag("web.listen-address", "sever listen port.").Default("9334").Int16()
+	webConfigFile          = kingpin.Flag("web.config.file", "Path to a configuration file enabling TLS, client certificate and basic authentication, in the Prometheus web-config format. Applies to the onboarding endpoint as well.").Default("").String()
+	metricsPath            = kingpin.Flag("web.metrics-path", "Path under which to expose metrics.").Default("/metrics").Envar("PG_EXPORTER_WEB_TELEMETRY_PATH").String()
+	disableDefaultMetrics  = kingpin.Flag("disable-default-metrics", "Do not include default metrics.").Default("false").Envar("PG_EXPORTER_DISABLE_DEFAULT_METRICS").Bool()
+	disableSettingsMetrics = kingpin.Flag("disable-settings-metrics", "Do not include pg_settings metrics.").Default("false").Envar("PG_EXPORTER_DISABLE_SETTINGS_METRICS").Bool()
+	metricPrefix           = kingpin.Flag("metric-prefix", "A metric prefix can be used to have non-default (not \"gs\") prefixes for each of the metrics").Default("gs").Envar("PG_EXPORTER_METRIC_PREFIX").String()
+	configFile             = kingpin.Flag("config.file", "Path to the config file.").Default(config.DefaultFile).String()
+	configDir              = kingpin.Flag("config.dir", "Directory of further config files, every *.yml in it adding instances to those of --config.file. --config.file may then be missing.").Default("").String()
+	configCheck            = kingpin.Flag("config.check", "Validate the config files and --web.config.file, report every problem found and exit.").Default("false").Bool()
+	encryptionKeyFile      = kingpin.Flag("config.encryption-key-file", "File holding the key for \"enc:\" passwords in config.yml. Defaults to the "+myencrypt.KeyEnv+" environment variable.").Default("").String()
+	historyKeyFile         = kingpin.Flag("install.history-key-file", "File holding the key the HISTORY file of the operations platform is encrypted with. Defaults to the "+myencrypt.HistoryKeyEnv+" environment variable, then to the deprecated built-in key. A HISTORY file under the built-in key is rewrapped under a configured one.").Default("").String()
+	encryptionAlgorithm    = kingpin.Flag("config.encryption-algorithm", "Algorithm used for values the exporter encrypts: aes256gcm or sm4gcm. Legacy DES values are still read.").Default(string(myencrypt.AES256GCM)).Enum(string(myencrypt.AES256GCM), string(myencrypt.SM4GCM))
+	logDir                 = kingpin.Flag("log.dir", "Directory to write log files to. Overridden by log.dir in config.yml.").Default(utils.DefaultOptions.Dir).String()
+	logMaxAge              = kingpin.Flag("log.max-age", "How long to keep rotated log files. Overridden by log.max_age in config.yml.").Default(utils.DefaultOptions.MaxAge.String()).Duration()
+	logRotationTime        = kingpin.Flag("log.rotation-time", "How often to start a new log file. Overridden by log.rotation_time in config.yml.").Default(utils.DefaultOptions.RotationTime.String()).Duration()
+	scrapeTimeoutOffset    = kingpin.Flag("scrape.timeout-offset", "Offset to subtract from the timeout Prometheus sends in the X-Prometheus-Scrape-Timeout-Seconds header, leaving time to write the response.").Default("500ms").Duration()
+	dbMaxOpenConns         = kingpin.Flag("db.max-open-conns", "Maximum number of open connections per instance, and so of collectors of an instance running at a time. Overridden by pool.max_open_conns in config.yml.").Default("2").Int()
+	dbMaxIdleConns         = kingpin.Flag("db.max-idle-conns", "Maximum number of idle connections kept per instance between scrapes. Overridden by pool.max_idle_conns in config.yml.").Default("2").Int()
+	dbMaxIdleTime          = kingpin.Flag("db.max-idle-time", "How long an idle connection is kept before it is closed. Overridden by pool.max_idle_time in config.yml.").Default("5m").Duration()
+	scrapeCacheTTL         = kingpin.Flag("scrape.cache-ttl", "How long to serve the result of a probe to further requests for the same instance and collectors. 0 only shares probes that are still running.").Default("0s").Duration()
+	scrapeConcurrency      = kingpin.Flag("scrape.concurrency", "Maximum number of instances probed at the same time when scraping all of them.").Default("4").Int()
+	configWatchInterval    = kingpin.Flag("config.watch-interval", "How often to check the config files for changes. 0 disables watching; SIGHUP and POST /-/reload still work.").Default("10s").Duration()
+
+	// exporterRegistry holds the exporter's own metrics. They are served
+	// alongside the probes of the default instance and of all instances;
+	// a probe for an instance_id only gets the series of that instance.
+	exporterRegistry = prometheus.NewRegistry()
+)
+
+// Metric name parts.
+const (
+	// Namespace for all metrics.
+	namespace = "gs"
+	// Subsystems.
+	exporter = "exporter"
+	// The name of the exporter.
+	exporterName = "gaussdb_exporter"
+	// Metric label used for static string data thats handy to send to Prometheus
+	// e.g. version
+	staticLabelName = "static"
+	// Metric label used for server identification.
+	serverLabelName = "server"
+)
+
+func main() {
+
+	var target_info myencrypt.Target_info
+
+	kingpin.Version(version.Print(exporterName))
+	flag.AddFlags(kingpin.CommandLine, promlogConfig)
+	kingpin.HelpFlag.Short('h')
+	command := kingpin.Parse()
+
+	myencrypt.DefaultAlgorithm = myencrypt.Algorithm(*encryptionAlgorithm)
+	myencrypt.HistoryKeyFile = *historyKeyFile
+	cfgHandler.File = *configFile
+	cfgHandler.Dir = *configDir
+	cfgHandler.Decrypt = decryptPassword
+	cfgHandler.KnownCollectors = collector.Names()
+	if err := utils.Configure(logOptions(nil)); err != nil {
+		fmt.Fprintln(os.Stderr, "Error configuring logger:", err)
+	}
+	logger := utils.GetLogger()
+	myencrypt.SetLogger(logger.With("component", "myencrypt"))
+	myencrypt.ListenAndServe = func(srv *http.Server) error {
+		return listenAndServe(srv, logger.With("component", "myencrypt"))
+	}
+	connections.Reload(poolOptionsFor(nil), nil)
+
+	switch command {
+	case encryptPasswordCmd.FullCommand():
+		os.Exit(runEncryptPassword())
+	case decryptPasswordCmd.FullCommand():
+		os.Exit(runDecryptPassword())
+	}
+
+	if *configCheck {
+		os.Exit(checkConfig())
+	}
+
+	if fi,err := os.Stat("HISTORY");err == nil || os.IsExist(err) {
+		logger.Info("监测到HISTORY文件，执行新模式")
+
+		if fi.Size() < 1 {
+			logger.Error("HISTORY文件是空的，部署有问题，无法继续")
+			os.Exit(1)
+		}
+
+		//从一体化运维平台接受参数并且生成配置文件
+		target_info = myencrypt.CheckInstall(fmt.Sprintf(":%d",*listenAddress))
+		config_file := cfgHandler.InitFromUrl(target_info.InstanceId,target_info.Excludedbs,target_info.Hosts,target_info.Port,target_info.DB,target_info.User,target_info.Password,"info",1)
+		cfgHandler.WriteConfigFile(config_file)
+		if err := reloadConfig(); err != nil {
+			logger.Warn("Error loading config", "err", err)
+		}
+
+
+	} else {
+		logger.Info("未监测到部署文件，不做任何改动，当前行为与官方包一致")
+		if err := reloadConfig(); err != nil {
+			logger.Warn("Error loading config", "err", err)
+		}
+	}
+
+	watchConfig(*configWatchInterval)
+
+	http.HandleFunc(*metricsPath, handleProbe(target_info.InstanceId))
+	http.HandleFunc(path.Join(*metricsPath, "all"), handleProbeAll)
+	http.HandleFunc("/sd", handleSD)
+	http.HandleFunc("/-/reload", handleReload)
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `<html>
+		<head><title>HuaWei GaussDB Exporter</title></head>
+		<body>
+		<h2>Welcome to use HuaWei GaussDB Exporter</h2>
+		</body>
+		</html>`)
+	})
+
+	logger.Info("服务已经启动", "listen_port", *listenAddress)
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", *listenAddress)}
+	if err := listenAndServe(srv, logger); err != nil {
+		logger.Error("无法启动web容器服务", "err", err)
+	}
+}
+
+// listenAndServe serves srv on its address, with the TLS and authentication
+// settings of --web.config.file.
+func listenAndServe(srv *http.Server, logger *utils.Logger) error {
+	addresses := []string{srv.Addr}
+	systemdSocket := false
+	return web.ListenAndServe(srv, &web.FlagConfig{
+		WebListenAddresses: &addresses,
+		WebSystemdSocket:   &systemdSocket,
+		WebConfigFile:      webConfigFile,
+	}, logger)
+}
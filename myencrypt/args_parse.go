@@ -1,211 +1,304 @@
-package myencrypt
-
-import (
-	"bytes"
-	"context"
-	"encoding/gob"
-	"encoding/hex"
-	"net/http"
-	"os"
-	"sync"
-	"log"
-)
-
-type Target_info struct {
-	InstanceId	string
-	Excludedbs	string
-	Hosts		string
-	Port		string
-	DB		string
-	User		string
-	Password	string
-}
-
-var (
-	logger = log.New(os.Stdout,"",log.Lshortfile | log.Ldate | log.Ltime)
-)
-
-func CheckInstall(listenAddress string) Target_info {
-	if fi, err := os.Stat("INSTALL"); err == nil || os.IsExist(err) {
-		if fi.Size() < 1 {
-			logger.Println("INSTALL文件是空的，请手动删除此文件后，重新纳管")
-			os.Exit(1)
-		}
-		data_encrypt, err := os.ReadFile("INSTALL")
-		if err != nil {
-			logger.Println(err)
-			logger.Println("检测到INSTALL文件，但无法打开，可能是权限问题")
-			os.Exit(1)
-		}
-		infohex, deserr := DesDecrypt(string(data_encrypt), getdefaultkey(), "")
-		if deserr != nil {
-			logger.Println(deserr)
-			logger.Println("解密INSTALL文件失败，如有必要，请手动删除此文件后，重新纳管")
-			os.Exit(1)
-		}
-
-		infobyte, _ := hex.DecodeString(infohex)
-		b := bytes.NewBuffer(infobyte)
-		infoDecoder := gob.NewDecoder(b)
-
-		var target_info Target_info
-		gobdecodeerr := infoDecoder.Decode(&target_info)
-		if gobdecodeerr != nil {
-			logger.Println(gobdecodeerr)
-			os.Exit(1)
-		}
-		return target_info
-
-	} else {
-		dealArgs(listenAddress)
-		// test
-		return CheckInstall(listenAddress)
-
-	}
-
-}
-
-func dealArgs(listenAddress string) {
-	serverDone := &sync.WaitGroup{}
-	serverDone.Add(1)
-	starthttp(serverDone, listenAddress)
-	serverDone.Wait()
-	logger.Println("url参数处理完毕")
-
-}
-
-var ctxShutdown, cancel = context.WithCancel(context.Background())
-
-func starthttp(wg *sync.WaitGroup, listenAddress string) {
-	mux := http.NewServeMux()
-	srv := &http.Server{Addr: listenAddress,Handler: mux}
-	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
-		select {
-		case <-ctxShutdown.Done():
-			logger.Println("ctxShutdown Done, exit")
-			return
-		default:
-		}
-
-		//从Url获取参数
-		vars := r.URL.Query()
-		logger.Printf("收到url参数: %v\n", string(vars.Encode()))
-		instance_id := vars.Get("instance_id")
-		if len(instance_id) == 0 {
-			instance_id = "gaussdb_default"
-			logger.Printf("set instance_id to %s",instance_id)
-		}
-		excludedbs := vars.Get("excludedbs")
-		if excludedbs == "" {
-			logger.Printf("excludedbs is null")
-		}
-		hosts	:= vars.Get("ip")
-		if hosts == "" {
-			hosts = "127.0.0.1"
-			logger.Printf("set host to default: %s",hosts)
-		}
-		port := vars.Get("port")
-		if port == "" {
-			port = "5432"
-			logger.Printf("set port to default: %s",port)
-		}
-		db := vars.Get("db")
-		if db == "" {
-			db = "postgres"
-			logger.Printf("set db to default: %s",db)
-		}
-		user, _ := DesDecrypt(vars.Get("user"), getdefaultkey(), "")
-		if user == ""{
-			user = "admin"
-			logger.Printf("set user to default: %s",user)
-		}
-		password, _ := DesDecrypt(vars.Get("password"), getdefaultkey(), "")
-		if password == "" {
-			password = "admin"
-			logger.Printf("set password to defult: %s",password)
-		}
-
-		if len(hosts) == 0 || len(port) == 0  {
-			logger.Print("Code runs into here url 参数不足")
-			w.WriteHeader(500)
-			w.Write([]byte("url参数不足或解析失败，如果正在重新纳管请稍等，否则请联系支持人员"))
-			return
-		}
-
-		var b bytes.Buffer
-		infoEncoder := gob.NewEncoder(&b)
-		infoEncoder.Encode(Target_info{instance_id, excludedbs, hosts, port, db, user, password})
-		infohex := hex.EncodeToString(b.Bytes())
-		info_des, deserr := DesEncrypt(infohex, getdefaultkey(), "")
-		if deserr != nil {
-			logger.Println(deserr)
-			w.WriteHeader(500)
-			w.Write([]byte("对相关参数解密时出现错误，请重试"))
-			return
-		}
-
-		install_file, err := os.Create("INSTALL")
-		if err != nil {
-			logger.Println(err)
-			w.WriteHeader(500)
-			w.Write([]byte("创建INSTALL文件失败，请检查运行目录及其权限"))
-			return
-		}
-		_, err2 := install_file.WriteString(info_des)
-		if err2 != nil {
-			logger.Println(err2)
-			w.WriteHeader(500)
-			w.Write([]byte("写入INSTALL文件失败，请检查运行目录及其权限"))
-			return
-		}
-		install_file.Close()
-		w.WriteHeader(200)
-		w.Write([]byte("登陆信息已保存，下次请求即可看到指标"))
-
-		cancel()
-		// graceful-shutdown
-		shutdownerr := srv.Shutdown(context.Background())
-		logger.Println("Hello world from shutdownerr")
-		if shutdownerr != nil {
-			logger.Println("temporary http server shutdown error", err)
-		}
-	})
-
-	go func() {
-		defer wg.Done()
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-			logger.Printf("ListenAndServe(): %v\n", err)
-		}
-
-		logger.Println("shutdown over")
-	}()
-}
-
-func getdefaultkey() string {
-	if fi, err := os.Stat("HISTORY"); err == nil || os.IsExist(err) {
-		if fi.Size() < 1 {
-			logger.Println("HISTORY文件是空的，部署有问题，无法继续")
-			os.Exit(1)
-		}
-
-		data_encrypt, err := os.ReadFile("HISTORY")
-
-		if err != nil {
-			logger.Println(err)
-			logger.Println("读取HISTORY文件内容失败，请检查文件权限")
-		}
-		key, deserr := DesDecrypt(string(data_encrypt), "e.X5T@h"+string([]byte{27}), "")
-		if deserr != nil {
-			logger.Println(deserr)
-			logger.Println("解密HISTORY文件失败，部署步骤可能有问题")
-		}
-		return key
-	} else {
-		if err != nil {
-			logger.Println(err)
-			logger.Println("HISTORY文件找不到，请检查部署步骤")
-			os.Exit(1)
-		}
-	}
-	return ""
-}
+package myencrypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+)
+
+type Target_info struct {
+	InstanceId	string
+	Excludedbs	string
+	Hosts		string
+	Port		string
+	DB		string
+	User		string
+	Password	string
+}
+
+// Logger is what myencrypt logs through. Messages are followed by
+// alternating keys and values.
+type Logger interface {
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+var (
+	logger Logger = stdLogger{log.New(os.Stderr, "", log.Lshortfile|log.Ldate|log.Ltime)}
+)
+
+// SetLogger replaces the logger used by myencrypt.
+func SetLogger(l Logger) {
+	logger = l
+}
+
+// stdLogger is the default Logger, writing through the standard library.
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s stdLogger) Info(msg string, keyvals ...interface{}) {
+	s.l.Output(2, fmt.Sprintln(append([]interface{}{"level=info", "msg=" + msg}, keyvals...)...))
+}
+
+func (s stdLogger) Warn(msg string, keyvals ...interface{}) {
+	s.l.Output(2, fmt.Sprintln(append([]interface{}{"level=warn", "msg=" + msg}, keyvals...)...))
+}
+
+func (s stdLogger) Error(msg string, keyvals ...interface{}) {
+	s.l.Output(2, fmt.Sprintln(append([]interface{}{"level=error", "msg=" + msg}, keyvals...)...))
+}
+
+func CheckInstall(listenAddress string) Target_info {
+	if fi, err := os.Stat("INSTALL"); err == nil || os.IsExist(err) {
+		if fi.Size() < 1 {
+			logger.Error("INSTALL文件是空的，请手动删除此文件后，重新纳管")
+			os.Exit(1)
+		}
+		data_encrypt, err := os.ReadFile("INSTALL")
+		if err != nil {
+			logger.Error("检测到INSTALL文件，但无法打开，可能是权限问题", "err", err)
+			os.Exit(1)
+		}
+		key, err := historyKey()
+		if err != nil {
+			logger.Error("获取HISTORY密钥失败，请检查部署步骤", "err", err)
+			os.Exit(1)
+		}
+		infohex, deserr := Decrypt(string(data_encrypt), key)
+		if deserr != nil {
+			logger.Error("解密INSTALL文件失败，如有必要，请手动删除此文件后，重新纳管", "err", deserr)
+			os.Exit(1)
+		}
+
+		infobyte, _ := hex.DecodeString(infohex)
+		b := bytes.NewBuffer(infobyte)
+		infoDecoder := gob.NewDecoder(b)
+
+		var target_info Target_info
+		gobdecodeerr := infoDecoder.Decode(&target_info)
+		if gobdecodeerr != nil {
+			logger.Error("解析INSTALL文件内容失败", "err", gobdecodeerr)
+			os.Exit(1)
+		}
+		return target_info
+
+	} else {
+		dealArgs(listenAddress)
+		// test
+		return CheckInstall(listenAddress)
+
+	}
+
+}
+
+func dealArgs(listenAddress string) {
+	serverDone := &sync.WaitGroup{}
+	serverDone.Add(1)
+	starthttp(serverDone, listenAddress)
+	serverDone.Wait()
+	logger.Info("url参数处理完毕")
+
+}
+
+var ctxShutdown, cancel = context.WithCancel(context.Background())
+
+// ListenAndServe runs the temporary server taking the instance settings. It
+// can be replaced to serve over TLS or require authentication, the way the
+// exporter's own endpoint does.
+var ListenAndServe = func(srv *http.Server) error {
+	return srv.ListenAndServe()
+}
+
+func starthttp(wg *sync.WaitGroup, listenAddress string) {
+	mux := http.NewServeMux()
+	srv := &http.Server{Addr: listenAddress,Handler: mux}
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-ctxShutdown.Done():
+			logger.Info("ctxShutdown Done, exit")
+			return
+		default:
+		}
+
+		//从Url获取参数
+		vars := r.URL.Query()
+		logger.Info("收到url参数", "params", vars.Encode())
+		instance_id := vars.Get("instance_id")
+		if len(instance_id) == 0 {
+			instance_id = "gaussdb_default"
+			logger.Info("set instance_id to default", "instance_id", instance_id)
+		}
+		excludedbs := vars.Get("excludedbs")
+		if excludedbs == "" {
+			logger.Info("excludedbs is null")
+		}
+		hosts	:= vars.Get("ip")
+		if hosts == "" {
+			hosts = "127.0.0.1"
+			logger.Info("set host to default", "host", hosts)
+		}
+		port := vars.Get("port")
+		if port == "" {
+			port = "5432"
+			logger.Info("set port to default", "port", port)
+		}
+		db := vars.Get("db")
+		if db == "" {
+			db = "postgres"
+			logger.Info("set db to default", "db", db)
+		}
+		key, err := historyKey()
+		if err != nil {
+			logger.Error("获取HISTORY密钥失败，请检查部署步骤", "err", err)
+			w.WriteHeader(500)
+			w.Write([]byte("获取HISTORY密钥失败，请联系支持人员"))
+			return
+		}
+		user, err := Decrypt(vars.Get("user"), key)
+		if err != nil {
+			logger.Error("解密url参数user失败", "err", err)
+			w.WriteHeader(400)
+			w.Write([]byte("url参数user解密失败"))
+			return
+		}
+		if user == ""{
+			user = "admin"
+			logger.Info("set user to default", "user", user)
+		}
+		password, err := Decrypt(vars.Get("password"), key)
+		if err != nil {
+			logger.Error("解密url参数password失败", "err", err)
+			w.WriteHeader(400)
+			w.Write([]byte("url参数password解密失败"))
+			return
+		}
+		if password == "" {
+			password = "admin"
+			logger.Info("set password to default")
+		}
+
+		if len(hosts) == 0 || len(port) == 0  {
+			logger.Error("url 参数不足")
+			w.WriteHeader(500)
+			w.Write([]byte("url参数不足或解析失败，如果正在重新纳管请稍等，否则请联系支持人员"))
+			return
+		}
+
+		var b bytes.Buffer
+		infoEncoder := gob.NewEncoder(&b)
+		infoEncoder.Encode(Target_info{instance_id, excludedbs, hosts, port, db, user, password})
+		infohex := hex.EncodeToString(b.Bytes())
+		info_des, deserr := Encrypt(infohex, key, DefaultAlgorithm)
+		if deserr != nil {
+			logger.Error("加密INSTALL文件内容失败", "err", deserr)
+			w.WriteHeader(500)
+			w.Write([]byte("对相关参数解密时出现错误，请重试"))
+			return
+		}
+
+		install_file, err := os.Create("INSTALL")
+		if err != nil {
+			logger.Error("创建INSTALL文件失败", "err", err)
+			w.WriteHeader(500)
+			w.Write([]byte("创建INSTALL文件失败，请检查运行目录及其权限"))
+			return
+		}
+		_, err2 := install_file.WriteString(info_des)
+		if err2 != nil {
+			logger.Error("写入INSTALL文件失败", "err", err2)
+			w.WriteHeader(500)
+			w.Write([]byte("写入INSTALL文件失败，请检查运行目录及其权限"))
+			return
+		}
+		install_file.Close()
+		w.WriteHeader(200)
+		w.Write([]byte("登陆信息已保存，下次请求即可看到指标"))
+
+		cancel()
+		// graceful-shutdown
+		shutdownerr := srv.Shutdown(context.Background())
+		if shutdownerr != nil {
+			logger.Error("temporary http server shutdown error", "err", shutdownerr)
+		}
+	})
+
+	go func() {
+		defer wg.Done()
+		if err := ListenAndServe(srv); err != http.ErrServerClosed {
+			logger.Error("ListenAndServe failed", "err", err)
+		}
+
+		logger.Info("shutdown over")
+	}()
+}
+
+// legacyHistoryKey is the key HISTORY files written by older deployments
+// are encrypted with. It is deprecated: HISTORY is only decrypted with it
+// when no key is configured, or when the configured key doesn't decrypt
+// HISTORY, which is then rewrapped under the configured key.
+const legacyHistoryKey = "e.X5T@h\x1b"
+
+// historyKey returns the key of the INSTALL file and the URL parameters. The
+// platform keeps it in the HISTORY file, encrypted under the key from
+// HistoryKeyFile or HistoryKeyEnv, or under legacyHistoryKey. It is
+// unrelated to the key of the "enc:" passwords in config.yml.
+func historyKey() (string, error) {
+	data, err := os.ReadFile("HISTORY")
+	if err != nil {
+		return "", fmt.Errorf("reading HISTORY: %w", err)
+	}
+	if len(data) == 0 {
+		return "", errors.New("HISTORY is empty")
+	}
+
+	wrapKey, err := loadKey(HistoryKeyFile, HistoryKeyEnv)
+	if err != nil {
+		logger.Warn("No HISTORY key configured, decrypting HISTORY with the deprecated built-in key", "err", err)
+		key, err := Decrypt(string(data), legacyHistoryKey)
+		if err != nil {
+			return "", fmt.Errorf("decrypting HISTORY with the built-in key: %w", err)
+		}
+		return key, nil
+	}
+
+	key, err := Decrypt(string(data), wrapKey)
+	if err == nil {
+		return key, nil
+	}
+	key, legacyErr := Decrypt(string(data), legacyHistoryKey)
+	if legacyErr != nil {
+		return "", fmt.Errorf("decrypting HISTORY: %w", err)
+	}
+	if err := rewrapHistory(key, wrapKey); err != nil {
+		logger.Error("Error rewrapping HISTORY under the configured key", "err", err)
+	} else {
+		logger.Info("Rewrapped HISTORY under the configured key")
+	}
+	return key, nil
+}
+
+// rewrapHistory replaces HISTORY with key encrypted under wrapKey.
+func rewrapHistory(key, wrapKey string) error {
+	wrapped, err := Encrypt(key, wrapKey, DefaultAlgorithm)
+	if err != nil {
+		return err
+	}
+	tmp := "HISTORY.tmp"
+	if err := os.WriteFile(tmp, []byte(wrapped), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, "HISTORY"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
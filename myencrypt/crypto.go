@@ -0,0 +1,120 @@
+package myencrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/tjfoc/gmsm/sm3"
+	"github.com/tjfoc/gmsm/sm4"
+)
+
+// Algorithm names an authenticated cipher supported by Encrypt.
+type Algorithm string
+
+const (
+	// AES256GCM is AES-256 in GCM mode.
+	AES256GCM Algorithm = "aes256gcm"
+	// SM4GCM is SM4 in GCM mode, for deployments that require domestic
+	// cryptography.
+	SM4GCM Algorithm = "sm4gcm"
+)
+
+// ciphertextVersion prefixes every value written by Encrypt. Values without
+// it are legacy DES-ECB hex strings.
+const ciphertextVersion = "v2"
+
+// DefaultAlgorithm is the algorithm used for values the exporter writes
+// itself, such as the INSTALL file.
+var DefaultAlgorithm = AES256GCM
+
+// ParseAlgorithm returns the Algorithm with the given name.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch alg := Algorithm(name); alg {
+	case AES256GCM, SM4GCM:
+		return alg, nil
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q", name)
+	}
+}
+
+// Encrypt encrypts value with a key derived from secretKey and returns it in
+// the versioned format "v2:<algorithm>:<base64url(nonce|ciphertext)>".
+func Encrypt(value, secretKey string, alg Algorithm) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	aead, err := newAEAD(alg, secretKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(alg))
+
+	return strings.Join([]string{ciphertextVersion, string(alg), base64.RawURLEncoding.EncodeToString(sealed)}, ":"), nil
+}
+
+// Decrypt reverses Encrypt. Values not in the versioned format are treated
+// as legacy DES-ECB ciphertext so that existing INSTALL files, URL
+// parameters and config passwords keep working.
+func Decrypt(value, secretKey string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 || parts[0] != ciphertextVersion {
+		return DesDecrypt(value, secretKey, "")
+	}
+
+	alg, err := ParseAlgorithm(parts[1])
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(alg, secretKey)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, []byte(alg))
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plain), nil
+}
+
+// newAEAD derives the cipher key from secretKey, using SHA-256 for AES and
+// SM3 for SM4, and returns the GCM mode of the cipher.
+func newAEAD(alg Algorithm, secretKey string) (cipher.AEAD, error) {
+	var block cipher.Block
+	var err error
+
+	switch alg {
+	case AES256GCM:
+		key := sha256.Sum256([]byte(secretKey))
+		block, err = aes.NewCipher(key[:])
+	case SM4GCM:
+		key := sm3.Sm3Sum([]byte(secretKey))
+		block, err = sm4.NewCipher(key[:sm4.BlockSize])
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
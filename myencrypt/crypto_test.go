@@ -0,0 +1,99 @@
+package myencrypt
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	for _, alg := range []Algorithm{AES256GCM, SM4GCM} {
+		ciphertext, err := Encrypt("monitor^123", "secret key", alg)
+		if err != nil {
+			t.Fatalf("%s: %s", alg, err)
+		}
+		if !strings.HasPrefix(ciphertext, "v2:"+string(alg)+":") {
+			t.Errorf("%s: unexpected format %q", alg, ciphertext)
+		}
+
+		plain, err := Decrypt(ciphertext, "secret key")
+		if err != nil {
+			t.Fatalf("%s: %s", alg, err)
+		}
+		if plain != "monitor^123" {
+			t.Errorf("%s: expected %q, got %q", alg, "monitor^123", plain)
+		}
+
+		if _, err := Decrypt(ciphertext, "other key"); err != ErrInvalidCiphertext {
+			t.Errorf("%s: expected ErrInvalidCiphertext with the wrong key, got %v", alg, err)
+		}
+		tampered := ciphertext[:len(ciphertext)-2] + "AA"
+		if _, err := Decrypt(tampered, "secret key"); err != ErrInvalidCiphertext {
+			t.Errorf("%s: expected ErrInvalidCiphertext for tampered value, got %v", alg, err)
+		}
+	}
+}
+
+func TestDecryptLegacy(t *testing.T) {
+	legacy, err := DesEncrypt("monitor^123", "gaussdb!", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := Decrypt(legacy, "gaussdb!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain != "monitor^123" {
+		t.Errorf("expected %q, got %q", "monitor^123", plain)
+	}
+
+	if _, err := Decrypt("not-hex", "gaussdb!"); err == nil {
+		t.Error("expected an error for malformed legacy value")
+	}
+}
+
+func TestHistoryKey(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	t.Setenv(KeyEnv, "config key")
+	t.Setenv(HistoryKeyEnv, "")
+	if _, err := historyKey(); err == nil {
+		t.Error("expected an error without a HISTORY file")
+	}
+
+	// A HISTORY file written by an older deployment is read with the
+	// built-in key when none is configured.
+	legacy, err := DesEncrypt("install key", legacyHistoryKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("HISTORY", []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if key, err := historyKey(); err != nil || key != "install key" {
+		t.Fatalf("expected the key from the legacy HISTORY, got %q, %v", key, err)
+	}
+
+	// Once a key is configured, the legacy HISTORY is rewrapped under it.
+	t.Setenv(HistoryKeyEnv, "history key")
+	if key, err := historyKey(); err != nil || key != "install key" {
+		t.Fatalf("expected the key from the legacy HISTORY, got %q, %v", key, err)
+	}
+	data, err := os.ReadFile("HISTORY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err := Decrypt(string(data), "history key"); err != nil || key != "install key" {
+		t.Errorf("expected HISTORY to be rewrapped under the configured key, got %q, %v", key, err)
+	}
+	if key, err := historyKey(); err != nil || key != "install key" {
+		t.Errorf("expected the key from the rewrapped HISTORY, got %q, %v", key, err)
+	}
+}
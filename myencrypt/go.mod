@@ -1,3 +1,5 @@
 module myencrypt
 
 go 1.21.5
+
+require github.com/tjfoc/gmsm v1.4.1
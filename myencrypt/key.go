@@ -0,0 +1,47 @@
+package myencrypt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyEnv is the environment variable holding the key used for encrypted
+// config values when no key file is given.
+const KeyEnv = "GS_EXPORTER_ENCRYPTION_KEY"
+
+// HistoryKeyEnv is the environment variable holding the key the HISTORY
+// file is encrypted with when HistoryKeyFile is not set.
+const HistoryKeyEnv = "GS_EXPORTER_HISTORY_KEY"
+
+// HistoryKeyFile is the file holding the key the HISTORY file is encrypted
+// with.
+var HistoryKeyFile string
+
+// LoadKey returns the key used to encrypt and decrypt config values. It is
+// read from keyFile if one is given, otherwise from the KeyEnv variable.
+func LoadKey(keyFile string) (string, error) {
+	return loadKey(keyFile, KeyEnv)
+}
+
+// loadKey reads a key from keyFile if one is given, otherwise from the
+// environment variable env.
+func loadKey(keyFile, env string) (string, error) {
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("reading key file: %w", err)
+		}
+		key := strings.TrimSpace(string(data))
+		if key == "" {
+			return "", fmt.Errorf("key file %q is empty", keyFile)
+		}
+		return key, nil
+	}
+
+	if key := os.Getenv(env); key != "" {
+		return key, nil
+	}
+	return "", errors.New("no encryption key configured, set " + env + " or provide a key file")
+}
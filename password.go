@@ -0,0 +1,119 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"golang.org/x/term"
+
+	"config"
+	"myencrypt"
+)
+
+var (
+	serveCmd           = kingpin.Command("serve", "Run the exporter.").Default()
+	encryptPasswordCmd = kingpin.Command("encrypt-password", "Encrypt a password read from stdin or a prompt and print it in the form config.yml accepts.")
+	decryptPasswordCmd = kingpin.Command("decrypt-password", "Decrypt a password read from stdin or a prompt, with or without its \"enc:\" prefix.")
+)
+
+// runEncryptPassword implements the encrypt-password command and returns the
+// process exit code.
+func runEncryptPassword() int {
+	key, err := myencrypt.LoadKey(*encryptionKeyFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	password, err := readSecret("Password: ", true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if password == "" {
+		fmt.Fprintln(os.Stderr, "refusing to encrypt an empty password")
+		return 1
+	}
+
+	ciphertext, err := myencrypt.Encrypt(password, key, myencrypt.DefaultAlgorithm)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(config.EncryptedPrefix + ciphertext)
+	return 0
+}
+
+// runDecryptPassword implements the decrypt-password command and returns the
+// process exit code.
+func runDecryptPassword() int {
+	key, err := myencrypt.LoadKey(*encryptionKeyFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ciphertext, err := readSecret("Encrypted password: ", false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	password, err := myencrypt.Decrypt(strings.TrimPrefix(ciphertext, config.EncryptedPrefix), key)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(password)
+	return 0
+}
+
+// readSecret reads a value without echoing it when stdin is a terminal, and
+// the whole of stdin otherwise, so that it never has to be passed as an
+// argument and end up in the shell history.
+func readSecret(prompt string, confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	value, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat "+strings.ToLower(prompt))
+		repeated, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(repeated) != string(value) {
+			return "", errors.New("values do not match")
+		}
+	}
+	return string(value), nil
+}
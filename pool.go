@@ -0,0 +1,267 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"config"
+)
+
+// connections holds the database pools shared by all scrapes.
+var connections = newConnManager()
+
+func init() {
+	exporterRegistry.MustRegister(connections)
+}
+
+// poolOptions are the limits applied to every pool.
+type poolOptions struct {
+	maxOpenConns int
+	maxIdleConns int
+	maxIdleTime  time.Duration
+}
+
+// poolOptionsFor merges the pool section of cfg over the command line flags.
+// cfg may be nil to use the flags alone.
+func poolOptionsFor(cfg *config.Config) poolOptions {
+	o := poolOptions{
+		maxOpenConns: *dbMaxOpenConns,
+		maxIdleConns: *dbMaxIdleConns,
+		maxIdleTime:  *dbMaxIdleTime,
+	}
+	if cfg == nil {
+		return o
+	}
+
+	if cfg.Pool.MaxOpenConns > 0 {
+		o.maxOpenConns = cfg.Pool.MaxOpenConns
+	}
+	if cfg.Pool.MaxIdleConns > 0 {
+		o.maxIdleConns = cfg.Pool.MaxIdleConns
+	}
+	if cfg.Pool.MaxIdleTime > 0 {
+		o.maxIdleTime = cfg.Pool.MaxIdleTime
+	}
+	return o
+}
+
+func (o poolOptions) apply(db *sql.DB) {
+	db.SetMaxOpenConns(o.maxOpenConns)
+	db.SetMaxIdleConns(o.maxIdleConns)
+	db.SetConnMaxIdleTime(o.maxIdleTime)
+}
+
+// pool is the database handle of one instance, and the server scraping it
+// through that handle. The server lives as long as the pool so that its
+// version and metric maps are kept between scrapes.
+type pool struct {
+	dsn    string
+	db     *sql.DB
+	server *Server
+
+	m *connManager
+	// refs counts the scrapes using the pool, plus one while it is the
+	// current pool of its instance. It is guarded by m.mtx, and the pool is
+	// closed once it drops to zero.
+	refs int
+}
+
+// Release gives back a pool returned by Get. A pool that has been replaced
+// or removed in the meantime is closed once its last user releases it.
+func (p *pool) Release() {
+	p.m.mtx.Lock()
+	defer p.m.mtx.Unlock()
+	p.m.unref(p)
+}
+
+// connManager keeps one long-lived database pool per instance_id, so that
+// scrapes don't have to connect and authenticate every time. It reports the
+// statistics of its pools as metrics.
+type connManager struct {
+	mtx   sync.Mutex
+	opts  poolOptions
+	pools map[string]*pool
+	// healthCheckFailures counts failed pings per instance_id. It outlives
+	// the pools, which are replaced after a failure.
+	healthCheckFailures map[string]float64
+}
+
+func newConnManager() *connManager {
+	return &connManager{
+		pools:               make(map[string]*pool),
+		healthCheckFailures: make(map[string]float64),
+	}
+}
+
+// Get returns the pool of instanceId, opening it on first use or when the
+// instance's dsn has changed. The pool is pinged before it is handed out; if
+// that fails it is retired and the error returned, and the next call starts
+// over with a new pool and server. A ping cut short by ctx says nothing about
+// the pool, which is kept. The caller must Release the pool when it is done
+// with it.
+func (m *connManager) Get(ctx context.Context, instanceId, dsn string) (*pool, error) {
+	m.mtx.Lock()
+	p, ok := m.pools[instanceId]
+	if ok && p.dsn != dsn {
+		m.retire(instanceId, p)
+		ok = false
+	}
+	if !ok {
+		db, err := sql.Open("opengauss", dsn)
+		if err != nil {
+			m.mtx.Unlock()
+			return nil, err
+		}
+		m.opts.apply(db)
+		logger := utils.GetLogger().With("instance_id", instanceId)
+		server, err := newPooledServer(dsn, db, ServerWithLogger(logger))
+		if err != nil {
+			db.Close()
+			m.mtx.Unlock()
+			return nil, err
+		}
+		server.master = true
+		p = &pool{dsn: dsn, db: db, server: server, m: m, refs: 1}
+		m.pools[instanceId] = p
+		utils.GetLogger().Info("Opened connection pool", "instance_id", instanceId)
+	}
+	p.refs++
+	m.mtx.Unlock()
+
+	if err := p.db.PingContext(ctx); err != nil {
+		m.mtx.Lock()
+		if ctx.Err() == nil {
+			m.healthCheckFailures[instanceId]++
+			if m.pools[instanceId] == p {
+				m.retire(instanceId, p)
+			}
+		}
+		m.unref(p)
+		m.mtx.Unlock()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Lookup returns the current pool of instanceId without checking it, or nil
+// if there is none. The caller must Release a pool returned.
+func (m *connManager) Lookup(instanceId string) *pool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	p := m.pools[instanceId]
+	if p != nil {
+		p.refs++
+	}
+	return p
+}
+
+// retire stops handing out p, the pool of instanceId, and closes it once
+// the scrapes still using it are done. m.mtx must be held.
+func (m *connManager) retire(instanceId string, p *pool) {
+	delete(m.pools, instanceId)
+	m.unref(p)
+}
+
+// unref drops a reference to p and closes it if that was the last one.
+// m.mtx must be held.
+func (m *connManager) unref(p *pool) {
+	p.refs--
+	if p.refs == 0 {
+		p.db.Close()
+	}
+}
+
+// Reload applies opts to every pool and closes the pools, and forgets the
+// health check failures, of instances that are no longer configured. Pools of instances whose connection settings
+// changed are replaced by Get on their next use.
+func (m *connManager) Reload(opts poolOptions, instanceIds []string) {
+	keep := make(map[string]bool, len(instanceIds))
+	for _, id := range instanceIds {
+		keep[id] = true
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.opts = opts
+	for id, p := range m.pools {
+		if !keep[id] {
+			m.retire(id, p)
+			utils.GetLogger().Info("Closed connection pool of removed instance", "instance_id", id)
+			continue
+		}
+		opts.apply(p.db)
+	}
+	for id := range m.healthCheckFailures {
+		if !keep[id] {
+			delete(m.healthCheckFailures, id)
+		}
+	}
+}
+
+var (
+	poolMaxOpenDesc           = newPoolDesc("max_open_connections", "Maximum number of open connections to the instance.")
+	poolOpenDesc              = newPoolDesc("open_connections", "Number of established connections to the instance, in use or idle.")
+	poolInUseDesc             = newPoolDesc("in_use_connections", "Number of connections to the instance currently in use.")
+	poolIdleDesc              = newPoolDesc("idle_connections", "Number of idle connections to the instance.")
+	poolWaitCountDesc         = newPoolDesc("wait_count_total", "Total number of times a scrape had to wait for a connection.")
+	poolWaitDurationDesc      = newPoolDesc("wait_duration_seconds_total", "Total time scrapes spent waiting for a connection.")
+	poolMaxIdleClosedDesc     = newPoolDesc("max_idle_closed_total", "Total number of connections closed because of the idle connection limit.")
+	poolMaxIdleTimeClosedDesc = newPoolDesc("max_idle_time_closed_total", "Total number of connections closed because they were idle for too long.")
+	poolHealthCheckDesc       = newPoolDesc("health_check_failures_total", "Total number of failed health checks of the pool.")
+)
+
+func newPoolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, exporter, "pool_"+name), help, []string{"instance_id"}, nil)
+}
+
+// Describe implements prometheus.Collector.
+func (m *connManager) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolMaxOpenDesc
+	ch <- poolOpenDesc
+	ch <- poolInUseDesc
+	ch <- poolIdleDesc
+	ch <- poolWaitCountDesc
+	ch <- poolWaitDurationDesc
+	ch <- poolMaxIdleClosedDesc
+	ch <- poolMaxIdleTimeClosedDesc
+	ch <- poolHealthCheckDesc
+}
+
+// Collect implements prometheus.Collector.
+func (m *connManager) Collect(ch chan<- prometheus.Metric) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	for id, p := range m.pools {
+		stats := p.db.Stats()
+		ch <- prometheus.MustNewConstMetric(poolMaxOpenDesc, prometheus.GaugeValue, float64(stats.MaxOpenConnections), id)
+		ch <- prometheus.MustNewConstMetric(poolOpenDesc, prometheus.GaugeValue, float64(stats.OpenConnections), id)
+		ch <- prometheus.MustNewConstMetric(poolInUseDesc, prometheus.GaugeValue, float64(stats.InUse), id)
+		ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(stats.Idle), id)
+		ch <- prometheus.MustNewConstMetric(poolWaitCountDesc, prometheus.CounterValue, float64(stats.WaitCount), id)
+		ch <- prometheus.MustNewConstMetric(poolWaitDurationDesc, prometheus.CounterValue, stats.WaitDuration.Seconds(), id)
+		ch <- prometheus.MustNewConstMetric(poolMaxIdleClosedDesc, prometheus.CounterValue, float64(stats.MaxIdleClosed), id)
+		ch <- prometheus.MustNewConstMetric(poolMaxIdleTimeClosedDesc, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed), id)
+	}
+	for id, failures := range m.healthCheckFailures {
+		ch <- prometheus.MustNewConstMetric(poolHealthCheckDesc, prometheus.CounterValue, failures, id)
+	}
+}
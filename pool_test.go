@@ -0,0 +1,82 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// closeConnector records whether the *sql.DB opened on it was closed.
+type closeConnector struct {
+	closed bool
+}
+
+func (c *closeConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("not connecting in tests")
+}
+
+func (c *closeConnector) Driver() driver.Driver { return nil }
+
+func (c *closeConnector) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestPoolClosedAfterLastRelease(t *testing.T) {
+	m := newConnManager()
+	connector := &closeConnector{}
+	// Held by the manager and by one scrape.
+	p := &pool{dsn: "a", db: sql.OpenDB(connector), m: m, refs: 2}
+	m.pools["a"] = p
+	m.healthCheckFailures["a"] = 1
+
+	m.Reload(poolOptions{}, nil)
+	if _, ok := m.pools["a"]; ok {
+		t.Fatal("expected the pool of the removed instance to be retired")
+	}
+	if _, ok := m.healthCheckFailures["a"]; ok {
+		t.Error("expected the health check failures of the removed instance to be forgotten")
+	}
+	if connector.closed {
+		t.Fatal("expected the pool to stay open while a scrape uses it")
+	}
+
+	p.Release()
+	if !connector.closed {
+		t.Error("expected the pool to be closed once released")
+	}
+}
+
+func TestPoolKeptAfterCancelledPing(t *testing.T) {
+	m := newConnManager()
+	connector := &closeConnector{}
+	p := &pool{dsn: "a", db: sql.OpenDB(connector), m: m, refs: 1}
+	m.pools["a"] = p
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.Get(ctx, "a", "a"); err == nil {
+		t.Fatal("expected the ping to fail")
+	}
+	if m.pools["a"] != p || connector.closed {
+		t.Error("expected the pool to be kept after a cancelled ping")
+	}
+	if n := m.healthCheckFailures["a"]; n != 0 {
+		t.Errorf("expected no health check failure, got %v", n)
+	}
+}
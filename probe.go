@@ -1,82 +1,280 @@
-// Copyright 2022 The Prometheus Authors
-// Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this file except in compliance with the License.
-// You may obtain a copy of the License at
-//
-// http://www.apache.org/licenses/LICENSE-2.0
-//
-// Unless required by applicable law or agreed to in writing, software
-// distributed under the License is distributed on an "AS IS" BASIS,
-// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-// See the License for the specific language governing permissions and
-// limitations under the License.
-
-package main
-
-import (
-	"fmt"
-	"net/http"
-
-	"github.com/prometheus-community/gaussdb_exporter/collector"
-	"github.com/prometheus-community/gaussdb_exporter/utils"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-)
-
-func handleProbe(instanceid string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		params := r.URL.Query()
-		instanceId := params.Get("instance_id")
-		if instanceId == "" {
-			//http.Error(w, "instance_id is required", http.StatusBadRequest)
-			//utils.GetLogger().Warn("instance_id is required")
-			instanceId = instanceid
-		}
-
-		dsn, err := getDataSourceById(instanceId)
-		if err != nil {
-			utils.GetLogger().Error("failed to configure target", "err", err)
-			http.Error(w, fmt.Sprintf("could not configure dsn for target: %v", err), http.StatusBadRequest)
-			return
-		}
-
-		registry := prometheus.NewRegistry()
-
-		opts := []ExporterOpt{
-			//返回func(e *Exporter)函数对象，设定传入的Exporter对象里面属性的值
-			DisableDefaultMetrics(*disableDefaultMetrics),
-			DisableSettingsMetrics(*disableSettingsMetrics),
-		}
-		//将opts传进来，其实就是初始化了exporter
-		exporter := NewExporter([]string{dsn}, opts...)
-		defer func() {
-			exporter.servers.Close()
-		}()
-
-		registry.MustRegister(exporter)
-
-		// Run the probe
-		pc, err := collector.NewProbeCollector(registry, dsn)
-		if err != nil {
-			utils.GetLogger().Error("Error creating probe collector", "err", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		// Cleanup underlying connections to prevent connection leaks
-		defer pc.Close()
-
-		// TODO(@sysadmind): Remove the registry.MustRegister() call below and instead handle the collection here. That will allow
-		// for the passing of context, handling of timeouts, and more control over the collection.
-		// The current NewProbeCollector() implementation relies on the MustNewConstMetric() call to create the metrics which is not
-		// ideal to use without the registry.MustRegister() call.
-		_ = ctx
-
-		registry.MustRegister(pc)
-
-		// TODO check success, etc
-		h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
-		h.ServeHTTP(w, r)
-	}
-}
+// Copyright 2022 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/gaussdb_exporter/collector"
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// errTarget marks errors about the instance a probe asked for, rather than
+// about scraping it.
+var errTarget = errors.New("could not configure dsn for target")
+
+func handleProbe(instanceid string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		instanceId := params.Get("instance_id")
+		if instanceId == allInstances {
+			handleProbeAll(w, r)
+			return
+		}
+		// A probe for an instance_id is one of several targets of this
+		// exporter, and only serves the exporter's series of that instance.
+		// The exporter-wide ones and those of other instances would
+		// otherwise be ingested once per target.
+		self := instanceGatherer(exporterRegistry, instanceId)
+		if instanceId == "" {
+			//http.Error(w, "instance_id is required", http.StatusBadRequest)
+			//utils.GetLogger().Warn("instance_id is required")
+			instanceId = instanceid
+			self = exporterRegistry
+		}
+
+		logger := utils.GetLogger().With("instance_id", instanceId)
+
+		ctx, cancel, err := scrapeContext(r)
+		if err != nil {
+			logger.Error("Error parsing scrape timeout", "err", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer cancel()
+
+		gatherer, err := gatherInstance(ctx, instanceId, params, logger)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errTarget) || errors.Is(err, collector.ErrUnknownCollector) || errors.Is(err, collector.ErrDisabledCollector) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		h := promhttp.HandlerFor(prometheus.Gatherers{self, gatherer}, promhttp.HandlerOpts{})
+		h.ServeHTTP(w, r)
+	}
+}
+
+// instanceGatherer gathers the series of g labeled with instanceId.
+func instanceGatherer(g prometheus.Gatherer, instanceId string) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		var result []*dto.MetricFamily
+		for _, mf := range families {
+			var metrics []*dto.Metric
+			for _, m := range mf.Metric {
+				for _, l := range m.Label {
+					if l.GetName() == "instance_id" && l.GetValue() == instanceId {
+						metrics = append(metrics, m)
+						break
+					}
+				}
+			}
+			if len(metrics) > 0 {
+				mf.Metric = metrics
+				result = append(result, mf)
+			}
+		}
+		return result, err
+	})
+}
+
+// gatherInstance returns the metrics of instanceId for a probe with params:
+// the last results of its background scheduler if it has one, or else those
+// of a probe run now or shared with others. The error is set if there are
+// no metrics to serve at all.
+func gatherInstance(ctx context.Context, instanceId string, params url.Values, logger *utils.Logger) (prometheus.Gatherer, error) {
+	if s := getScheduler(instanceId); s != nil {
+		return gatherBackground(s, params, instanceId, logger)
+	}
+
+	dsn, err := getDataSourceById(instanceId)
+	if err != nil {
+		logger.Error("failed to configure target", "err", err)
+		return nil, fmt.Errorf("%w: %v", errTarget, err)
+	}
+
+	// Requests for the same instance and collectors arriving while a
+	// probe is running share its result instead of starting their own.
+	key := probeKey(instanceId, params["collect[]"], params["exclude[]"])
+	result, source := probes.Do(ctx, key, *scrapeCacheTTL, func(ctx context.Context) ([]*dto.MetricFamily, error) {
+		return runProbe(ctx, instanceId, dsn, params, logger)
+	})
+	probeRequests.WithLabelValues(instanceId, source).Inc()
+	if result.families == nil {
+		return nil, result.err
+	}
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return result.families, result.err
+	}), nil
+}
+
+// runProbe collects the metrics of an instance. It returns nil families if
+// the probe couldn't be set up, and the families gathered so far along with
+// the error if collecting failed.
+func runProbe(ctx context.Context, instanceId, dsn string, params url.Values, logger *utils.Logger) ([]*dto.MetricFamily, error) {
+	// Without a healthy pool the probe falls back to connecting on its
+	// own, so that the failure is reported in the usual metrics.
+	begun := time.Now()
+	pool, err := connections.Get(ctx, instanceId, dsn)
+	recordConnect(instanceId, time.Since(begun), err)
+	if err != nil {
+		logger.Error("Connection pool health check failed", "err", err)
+	} else {
+		defer pool.Release()
+	}
+
+	registry := prometheus.NewRegistry()
+	// Everything scraped is labeled with the instance it came from.
+	registerer := prometheus.WrapRegistererWith(prometheus.Labels{"instance_id": instanceId}, registry)
+
+	databases := databaseFilter(instanceId)
+	opts := []ExporterOpt{
+		//返回func(e *Exporter)函数对象，设定传入的Exporter对象里面属性的值
+		DisableDefaultMetrics(*disableDefaultMetrics),
+		DisableSettingsMetrics(*disableSettingsMetrics),
+		WithDatabases(databases),
+		WithLogger(logger),
+		WithContext(ctx),
+	}
+	if pool != nil {
+		opts = append(opts, WithServer(dsn, pool.server))
+	}
+	//将opts传进来，其实就是初始化了exporter
+	exporter := NewExporter([]string{dsn}, opts...)
+	defer func() {
+		exporter.servers.Close()
+	}()
+
+	registerer.MustRegister(exporter)
+
+	// Run the probe
+	probeOpts := []collector.ProbeOpt{
+		collector.ProbeWithContext(ctx),
+		collector.ProbeWithLogger(logger),
+		collector.ProbeCollect(params["collect[]"]),
+		collector.ProbeExclude(params["exclude[]"]),
+		collector.ProbeWithOverrides(collectorOverrides(instanceId)),
+		collector.ProbeWithDatabases(databases),
+	}
+	if pool != nil {
+		probeOpts = append(probeOpts,
+			collector.ProbeWithDB(pool.db),
+			collector.ProbeWithVersion(func(ctx context.Context) (semver.Version, error) {
+				version, _, err := pool.server.Version(ctx)
+				return version, err
+			}),
+		)
+	}
+	if cfg := cfgHandler.GetConfig(); cfg != nil {
+		probeOpts = append(probeOpts, collector.ProbeWithTimeouts(cfg.Collector.Timeout, cfg.Collector.Timeouts))
+	}
+	pc, err := collector.NewProbeCollector(registry, dsn, probeOpts...)
+	if err != nil {
+		logger.Error("Error creating probe collector", "err", err)
+		return nil, err
+	}
+
+	// Cleanup underlying connections to prevent connection leaks
+	defer pc.Close()
+
+	registerer.MustRegister(pc)
+
+	families, err := registry.Gather()
+	if families == nil {
+		families = []*dto.MetricFamily{}
+	}
+	addLabels(families, instanceLabels(instanceId), logger)
+	return families, err
+}
+
+// addLabels adds labels to every metric of families. The labels of the
+// collectors and user queries are only known once they have run, so a
+// metric that already has one of the labels keeps its own value, and the
+// clash is logged.
+func addLabels(families []*dto.MetricFamily, labels prometheus.Labels, logger *utils.Logger) {
+	if len(labels) == 0 {
+		return
+	}
+
+	clashes := make(map[string][]string)
+	for _, mf := range families {
+		clashed := make(map[string]bool)
+		for _, m := range mf.Metric {
+			has := make(map[string]bool, len(m.Label))
+			for _, l := range m.Label {
+				has[l.GetName()] = true
+			}
+			for name, value := range labels {
+				if has[name] {
+					clashed[name] = true
+					continue
+				}
+				name, value := name, value
+				m.Label = append(m.Label, &dto.LabelPair{Name: &name, Value: &value})
+			}
+			sort.Slice(m.Label, func(i, j int) bool {
+				return m.Label[i].GetName() < m.Label[j].GetName()
+			})
+		}
+		for name := range clashed {
+			clashes[name] = append(clashes[name], mf.GetName())
+		}
+	}
+	for name, metrics := range clashes {
+		logger.Warn("Configured label not added to metrics that already have it", "label", name, "metrics", strings.Join(metrics, ","))
+	}
+}
+
+// scrapeContext returns the context a probe runs under. It is cancelled with
+// the request, or once the timeout Prometheus sends in the
+// X-Prometheus-Scrape-Timeout-Seconds header, minus --scrape.timeout-offset,
+// has passed. A probe shared by several requests is only cancelled when all
+// of them are.
+func scrapeContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		ctx, cancel := context.WithCancel(r.Context())
+		return ctx, cancel, nil
+	}
+
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return nil, nil, fmt.Errorf("invalid X-Prometheus-Scrape-Timeout-Seconds header %q", header)
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	// Keep the full timeout rather than none if the offset is too large.
+	if timeout > *scrapeTimeoutOffset {
+		timeout -= *scrapeTimeoutOffset
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return ctx, cancel, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// allInstances is the instance_id that asks for every configured instance,
+// like the /all path under --web.metrics-path does.
+const allInstances = "*"
+
+// handleProbeAll probes every configured instance, at most
+// --scrape.concurrency at a time, and serves their metrics together. Every
+// series carries the instance_id it came from. Instances that can't be
+// probed are logged and left out rather than failing the whole response.
+func handleProbeAll(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	logger := utils.GetLogger()
+
+	ctx, cancel, err := scrapeContext(r)
+	if err != nil {
+		logger.Error("Error parsing scrape timeout", "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+
+	var instanceIds []string
+	if cfg := cfgHandler.GetConfig(); cfg != nil {
+		for _, instance := range cfg.Instances {
+			instanceIds = append(instanceIds, instance.InstanceId)
+		}
+	}
+
+	workers := *scrapeConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	gatherers := make([]prometheus.Gatherer, len(instanceIds))
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for n := 0; n < workers && n < len(instanceIds); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				logger := logger.With("instance_id", instanceIds[i])
+				g, err := gatherInstance(ctx, instanceIds[i], params, logger)
+				if err != nil {
+					logger.Error("Leaving instance out of the scrape of all instances", "err", err)
+					continue
+				}
+				gatherers[i] = g
+			}
+		}()
+	}
+	for i := range instanceIds {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	all := prometheus.Gatherers{exporterRegistry}
+	for _, g := range gatherers {
+		if g != nil {
+			all = append(all, g)
+		}
+	}
+	h := promhttp.HandlerFor(all, promhttp.HandlerOpts{
+		ErrorLog:      promhttpLogger{logger},
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+	h.ServeHTTP(w, r)
+}
+
+// promhttpLogger passes the errors promhttp skips over on to logger.
+type promhttpLogger struct {
+	logger *utils.Logger
+}
+
+func (l promhttpLogger) Println(v ...interface{}) {
+	l.logger.Error("Error gathering metrics of all instances", "err", fmt.Sprint(v...))
+}
@@ -0,0 +1,185 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/exporter-toolkit/web"
+
+	"config"
+	"myencrypt"
+)
+
+var (
+	reloadMtx sync.Mutex
+
+	configReloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: exporter,
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last configuration reload attempt was successful (1 for success, 0 for failure).",
+	})
+	configReloadSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: exporter,
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "Timestamp of the last successful configuration reload.",
+	})
+)
+
+func init() {
+	exporterRegistry.MustRegister(configReloadSuccess, configReloadSeconds)
+}
+
+// reloadConfig loads the config files through cfgHandler and records the
+// outcome. On failure the previously loaded config stays active.
+func reloadConfig() error {
+	reloadMtx.Lock()
+	defer reloadMtx.Unlock()
+
+	if err := cfgHandler.ReloadConfig(); err != nil {
+		configReloadSuccess.Set(0)
+		return err
+	}
+	configReloadSuccess.Set(1)
+	configReloadSeconds.SetToCurrentTime()
+
+	cfg := cfgHandler.GetConfig()
+	if err := utils.Configure(logOptions(cfg)); err != nil {
+		utils.GetLogger().Error("Error applying log config", "err", err)
+	}
+
+	instanceIds := make([]string, len(cfg.Instances))
+	for i, instance := range cfg.Instances {
+		instanceIds[i] = instance.InstanceId
+	}
+	connections.Reload(poolOptionsFor(cfg), instanceIds)
+	restartBackground(cfg)
+	return nil
+}
+
+// logOptions merges the log section of cfg over the command line flags.
+// cfg may be nil to use the flags alone.
+func logOptions(cfg *config.Config) utils.Options {
+	o := utils.Options{
+		Level:        promlogConfig.Level.String(),
+		Format:       promlogConfig.Format.String(),
+		Dir:          *logDir,
+		MaxAge:       *logMaxAge,
+		RotationTime: *logRotationTime,
+	}
+	if cfg == nil {
+		return o
+	}
+
+	if cfg.Log.Level != "" {
+		o.Level = cfg.Log.Level
+	}
+	if cfg.Log.Format != "" {
+		o.Format = cfg.Log.Format
+	}
+	if cfg.Log.Dir != "" {
+		o.Dir = cfg.Log.Dir
+	}
+	if cfg.Log.MaxAge > 0 {
+		o.MaxAge = time.Duration(cfg.Log.MaxAge) * 24 * time.Hour
+	}
+	if cfg.Log.RotationTime > 0 {
+		o.RotationTime = cfg.Log.RotationTime
+	}
+	return o
+}
+
+// decryptPassword decrypts an "enc:" password from config.yml with the
+// configured key.
+func decryptPassword(ciphertext string) (string, error) {
+	key, err := myencrypt.LoadKey(*encryptionKeyFile)
+	if err != nil {
+		return "", err
+	}
+	return myencrypt.Decrypt(ciphertext, key)
+}
+
+// checkConfig validates the config files without starting the exporter and
+// prints every problem found. It returns the process exit code.
+func checkConfig() int {
+	if *webConfigFile != "" {
+		if err := web.Validate(*webConfigFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error in web config %q: %s\n", *webConfigFile, err)
+			return 1
+		}
+	}
+	if _, err := cfgHandler.LoadConfig(); err != nil {
+		var verrs config.ValidationErrors
+		if !errors.As(err, &verrs) {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Found %d problem(s) in the config:\n", len(verrs))
+		for _, verr := range verrs {
+			fmt.Fprintf(os.Stderr, "  %s\n", verr)
+		}
+		return 1
+	}
+	fmt.Println("Config is valid")
+	return 0
+}
+
+// watchConfig reloads the config on SIGHUP and, if interval is positive,
+// whenever the config file changes on disk.
+func watchConfig(interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			utils.GetLogger().Info("Received SIGHUP, reloading config")
+			if err := reloadConfig(); err != nil {
+				utils.GetLogger().Error("Error reloading config", "err", err)
+			}
+		}
+	}()
+
+	if interval > 0 {
+		go cfgHandler.Watch(interval, func() {
+			utils.GetLogger().Info("Config file changed, reloading config")
+			if err := reloadConfig(); err != nil {
+				utils.GetLogger().Error("Error reloading config", "err", err)
+			}
+		})
+	}
+}
+
+// handleReload triggers a config reload on POST /-/reload.
+func handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "This endpoint requires a POST request.", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := reloadConfig(); err != nil {
+		utils.GetLogger().Error("Error reloading config", "err", err)
+		http.Error(w, fmt.Sprintf("failed to reload config: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+
+	"config"
+)
+
+// targetGroup is a group of targets in the Prometheus http_sd format.
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// targetGroups returns a target group per instance of cfg, each probing the
+// instance through this exporter at address and metricsPath.
+func targetGroups(cfg *config.Config, address, metricsPath string) []targetGroup {
+	groups := []targetGroup{}
+	if cfg == nil {
+		return groups
+	}
+
+	for _, instance := range cfg.Instances {
+		labels := map[string]string{
+			"__metrics_path__":    metricsPath,
+			"__param_instance_id": instance.InstanceId,
+		}
+		for name, value := range instance.Labels {
+			labels[name] = value
+		}
+		labels["instance_id"] = instance.InstanceId
+		groups = append(groups, targetGroup{Targets: []string{address}, Labels: labels})
+	}
+	return groups
+}
+
+// handleSD serves the configured instances for Prometheus' http_sd_configs,
+// as targets at the address the request was sent to. The metrics of a probe
+// already carry the instance labels, so the scrape job should set
+// honor_labels to keep them from being renamed to exported_*.
+func handleSD(w http.ResponseWriter, r *http.Request) {
+	groups := targetGroups(cfgHandler.GetConfig(), r.Host, *metricsPath)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		utils.GetLogger().Error("Error writing service discovery response", "err", err)
+	}
+}
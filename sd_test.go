@@ -0,0 +1,42 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"config"
+)
+
+func TestTargetGroups(t *testing.T) {
+	cfg := &config.Config{Instances: []config.Instance{
+		{InstanceId: "a"},
+		{InstanceId: "b", Labels: map[string]string{"env": "prod"}},
+	}}
+
+	b, err := json.Marshal(targetGroups(cfg, "exporter:9334", "/metrics"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"targets":["exporter:9334"],"labels":{"__metrics_path__":"/metrics","__param_instance_id":"a","instance_id":"a"}},` +
+		`{"targets":["exporter:9334"],"labels":{"__metrics_path__":"/metrics","__param_instance_id":"b","env":"prod","instance_id":"b"}}]`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+
+	if b, _ := json.Marshal(targetGroups(nil, "exporter:9334", "/metrics")); string(b) != "[]" {
+		t.Errorf("expected no targets without a config, got %s", b)
+	}
+}
@@ -1,191 +1,262 @@
-// Copyright 2021 The Prometheus Authors
-// Licensed under the Apache License, Version 2.0 (the "License");
-// you may not use this file except in compliance with the License.
-// You may obtain a copy of the License at
-//
-// http://www.apache.org/licenses/LICENSE-2.0
-//
-// Unless required by applicable law or agreed to in writing, software
-// distributed under the License is distributed on an "AS IS" BASIS,
-// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
-// See the License for the specific language governing permissions and
-// limitations under the License.
-
-package main
-
-import (
-	"database/sql"
-	"fmt"
-	"sync"
-	"time"
-
-	_ "gitee.com/opengauss/openGauss-connector-go-pq"
-	"github.com/blang/semver/v4"
-	"github.com/prometheus-community/gaussdb_exporter/utils"
-	"github.com/prometheus/client_golang/prometheus"
-)
-
-// Server describes a connection to Postgres.
-// Also it contains metrics map and query overrides.
-type Server struct {
-	db          *sql.DB
-	labels      prometheus.Labels
-	master      bool
-	runonserver string
-
-	// Last version used to calculate metric map. If mismatch on scrape,
-	// then maps are recalculated.
-	lastMapVersion semver.Version
-	// Currently active metric map
-	metricMap map[string]MetricMapNamespace
-	// Currently active query overrides
-	queryOverrides map[string]string
-	mappingMtx     sync.RWMutex
-	// Currently cached metrics
-	metricCache map[string]cachedMetrics
-	cacheMtx    sync.Mutex
-}
-
-// ServerOpt configures a server.
-type ServerOpt func(*Server)
-
-// ServerWithLabels configures a set of labels.
-func ServerWithLabels(labels prometheus.Labels) ServerOpt {
-	return func(s *Server) {
-		for k, v := range labels {
-			s.labels[k] = v
-		}
-	}
-}
-
-// NewServer establishes a new connection using DSN.
-func NewServer(dsn string, opts ...ServerOpt) (*Server, error) {
-	fingerprint, err := parseFingerprint(dsn)
-	if err != nil {
-		return nil, err
-	}
-
-	db, err := sql.Open("opengauss", dsn)
-	if err != nil {
-		return nil, err
-	}
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
-
-	utils.GetLogger().Info("Established new database connection", "fingerprint", fingerprint)
-
-	s := &Server{
-		db:     db,
-		master: false,
-		labels: prometheus.Labels{
-			serverLabelName: fingerprint,
-		},
-		metricCache: make(map[string]cachedMetrics),
-	}
-
-	for _, opt := range opts {
-		opt(s)
-	}
-
-	return s, nil
-}
-
-// Close disconnects from Postgres.
-func (s *Server) Close() error {
-	return s.db.Close()
-}
-
-// Ping checks connection availability and possibly invalidates the connection if it fails.
-func (s *Server) Ping() error {
-	if err := s.db.Ping(); err != nil {
-		if cerr := s.Close(); cerr != nil {
-			utils.GetLogger().Error("Error while closing non-pinging DB connection", "server", s, "err", cerr)
-		}
-		return err
-	}
-	return nil
-}
-
-// String returns server's fingerprint.
-func (s *Server) String() string {
-	return s.labels[serverLabelName]
-}
-
-// Scrape loads metrics.
-func (s *Server) Scrape(ch chan<- prometheus.Metric, disableSettingsMetrics bool) error {
-	s.mappingMtx.RLock()
-	defer s.mappingMtx.RUnlock()
-
-	var err error
-
-	if !disableSettingsMetrics && s.master {
-		if err = querySettings(ch, s); err != nil {
-			err = fmt.Errorf("error retrieving settings: %s", err)
-		}
-	}
-
-	errMap := queryNamespaceMappings(ch, s)
-	if len(errMap) > 0 {
-		err = fmt.Errorf("queryNamespaceMappings returned %d errors", len(errMap))
-	}
-
-	return err
-}
-
-// Servers contains a collection of servers to Postgres.
-type Servers struct {
-	m       sync.Mutex
-	servers map[string]*Server
-	opts    []ServerOpt
-}
-
-// NewServers creates a collection of servers to Postgres.
-func NewServers(opts ...ServerOpt) *Servers {
-	return &Servers{
-		servers: make(map[string]*Server),
-		opts:    opts,
-	}
-}
-
-// GetServer returns established connection from a collection.
-func (s *Servers) GetServer(dsn string) (*Server, error) {
-	s.m.Lock()
-	defer s.m.Unlock()
-	var err error
-	var ok bool
-	errCount := 0 // start at zero because we increment before doing work
-	retries := 1
-	var server *Server
-	for {
-		if errCount++; errCount > retries {
-			return nil, err
-		}
-		server, ok = s.servers[dsn]
-		if !ok {
-			server, err = NewServer(dsn, s.opts...)
-			if err != nil {
-				time.Sleep(time.Duration(errCount) * time.Second)
-				continue
-			}
-			s.servers[dsn] = server
-		}
-		if err = server.Ping(); err != nil {
-			delete(s.servers, dsn)
-			time.Sleep(time.Duration(errCount) * time.Second)
-			continue
-		}
-		break
-	}
-	return server, nil
-}
-
-// Close disconnects from all known servers.
-func (s *Servers) Close() {
-	s.m.Lock()
-	defer s.m.Unlock()
-	for _, server := range s.servers {
-		if err := server.Close(); err != nil {
-			utils.GetLogger().Error("Failed to close connection", "server", server, "err", err)
-		}
-	}
-}
+// Copyright 2021 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "gitee.com/opengauss/openGauss-connector-go-pq"
+	"github.com/blang/semver/v4"
+	"github.com/prometheus-community/gaussdb_exporter/collector"
+	"github.com/prometheus-community/gaussdb_exporter/utils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Server describes a connection to Postgres.
+// Also it contains metrics map and query overrides.
+type Server struct {
+	db *sql.DB
+	// pooled is set if db belongs to the connection manager, in which case
+	// the server must not close it.
+	pooled bool
+	labels prometheus.Labels
+	// master is set on pooled servers when they are created and not
+	// written afterwards, since concurrent scrapes share them.
+	master      bool
+	runonserver string
+	logger      *utils.Logger
+
+	// Last version used to calculate metric map. If mismatch on scrape,
+	// then maps are recalculated.
+	lastMapVersion semver.Version
+	// Currently active metric map
+	metricMap map[string]MetricMapNamespace
+	// Currently active query overrides
+	queryOverrides map[string]string
+	mappingMtx     sync.RWMutex
+	// Currently cached metrics
+	metricCache map[string]cachedMetrics
+	cacheMtx    sync.Mutex
+
+	// Version as last queried, see Version.
+	semanticVersion semver.Version
+	versionString   string
+	versionMtx      sync.Mutex
+}
+
+// ServerOpt configures a server.
+type ServerOpt func(*Server)
+
+// ServerWithLabels configures a set of labels.
+func ServerWithLabels(labels prometheus.Labels) ServerOpt {
+	return func(s *Server) {
+		for k, v := range labels {
+			s.labels[k] = v
+		}
+	}
+}
+
+// ServerWithLogger configures the logger; the server adds its fingerprint.
+func ServerWithLogger(logger *utils.Logger) ServerOpt {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// NewServer establishes a new connection using DSN.
+func NewServer(dsn string, opts ...ServerOpt) (*Server, error) {
+	db, err := sql.Open("opengauss", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	s, err := newServer(dsn, db, opts...)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// newPooledServer creates a server for dsn that queries through db, a
+// handle owned by the connection manager.
+func newPooledServer(dsn string, db *sql.DB, opts ...ServerOpt) (*Server, error) {
+	s, err := newServer(dsn, db, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.pooled = true
+	return s, nil
+}
+
+func newServer(dsn string, db *sql.DB, opts ...ServerOpt) (*Server, error) {
+	fingerprint, err := parseFingerprint(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		db:     db,
+		master: false,
+		labels: prometheus.Labels{
+			serverLabelName: fingerprint,
+		},
+		logger:      utils.GetLogger(),
+		metricCache: make(map[string]cachedMetrics),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.logger = s.logger.With("server", fingerprint)
+
+	s.logger.Info("Established new database connection")
+
+	return s, nil
+}
+
+// Version returns the server version. It is queried once and then cached for
+// the life of the server, which ends when its connection fails, so a server
+// that was upgraded is detected when the exporter reconnects to it.
+func (s *Server) Version(ctx context.Context) (semver.Version, string, error) {
+	s.versionMtx.Lock()
+	defer s.versionMtx.Unlock()
+	if s.versionString != "" {
+		return s.semanticVersion, s.versionString, nil
+	}
+
+	semanticVersion, versionString, err := checkPostgresVersion(ctx, s.db, s.String(), s.logger)
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+	s.semanticVersion, s.versionString = semanticVersion, versionString
+	return semanticVersion, versionString, nil
+}
+
+// Close disconnects from Postgres, unless the connection is pooled.
+func (s *Server) Close() error {
+	if s.pooled {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// Ping checks connection availability and possibly invalidates the connection if it fails.
+func (s *Server) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		if cerr := s.Close(); cerr != nil {
+			s.logger.Error("Error while closing non-pinging DB connection", "err", cerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// String returns server's fingerprint.
+func (s *Server) String() string {
+	return s.labels[serverLabelName]
+}
+
+// Scrape loads metrics, leaving out the per-database ones of the databases
+// skipped by databases.
+func (s *Server) Scrape(ctx context.Context, ch chan<- prometheus.Metric, disableSettingsMetrics bool, databases collector.DatabaseFilter) error {
+	s.mappingMtx.RLock()
+	defer s.mappingMtx.RUnlock()
+
+	var err error
+
+	if !disableSettingsMetrics && s.master {
+		if err = querySettings(ctx, ch, s); err != nil {
+			err = fmt.Errorf("error retrieving settings: %s", err)
+		}
+	}
+
+	errMap := queryNamespaceMappings(ctx, ch, s, databases)
+	if len(errMap) > 0 {
+		err = fmt.Errorf("queryNamespaceMappings returned %d errors", len(errMap))
+	}
+
+	return err
+}
+
+// Servers contains a collection of servers to Postgres.
+type Servers struct {
+	m       sync.Mutex
+	servers map[string]*Server
+	opts    []ServerOpt
+	// shared are servers kept by the connection manager, to use for some
+	// of the DSNs instead of setting up servers of their own.
+	shared map[string]*Server
+}
+
+// NewServers creates a collection of servers to Postgres.
+func NewServers(opts ...ServerOpt) *Servers {
+	return &Servers{
+		servers: make(map[string]*Server),
+		opts:    opts,
+	}
+}
+
+// GetServer returns established connection from a collection.
+func (s *Servers) GetServer(ctx context.Context, dsn string) (*Server, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	var err error
+	var ok bool
+	errCount := 0 // start at zero because we increment before doing work
+	retries := 1
+	var server *Server
+	for {
+		if errCount++; errCount > retries {
+			return nil, err
+		}
+		server, ok = s.servers[dsn]
+		if !ok {
+			if shared, ok := s.shared[dsn]; ok {
+				server = shared
+			} else {
+				server, err = NewServer(dsn, s.opts...)
+			}
+			if err != nil {
+				time.Sleep(time.Duration(errCount) * time.Second)
+				continue
+			}
+			s.servers[dsn] = server
+		}
+		if err = server.Ping(ctx); err != nil {
+			delete(s.servers, dsn)
+			time.Sleep(time.Duration(errCount) * time.Second)
+			continue
+		}
+		break
+	}
+	return server, nil
+}
+
+// Close disconnects from all known servers.
+func (s *Servers) Close() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	for _, server := range s.servers {
+		if err := server.Close(); err != nil {
+			server.logger.Error("Failed to close connection", "err", err)
+		}
+	}
+}
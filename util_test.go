@@ -0,0 +1,113 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"config"
+)
+
+func TestBuildDSN(t *testing.T) {
+	instance := config.Instance{
+		Host:               "db1,db2",
+		Port:               "5432",
+		Db:                 "postgres",
+		User:               "monitor",
+		SSLMode:            "verify-full",
+		SSLRootCert:        "/etc/ssl/root.crt",
+		ConnectTimeout:     1500 * time.Millisecond,
+		ApplicationName:    "gaussdb exporter",
+		TargetSessionAttrs: "read-write",
+	}
+	password := `it's a \secret`
+
+	dsn := buildDSN(instance, password)
+	kv, err := parseConnString(dsn)
+	if err != nil {
+		t.Fatalf("parsing %q: %s", dsn, err)
+	}
+	want := map[string]string{
+		"host":                 "db1,db2",
+		"port":                 "5432",
+		"dbname":               "postgres",
+		"user":                 "monitor",
+		"password":             password,
+		"sslmode":              "verify-full",
+		"sslrootcert":          "/etc/ssl/root.crt",
+		"connect_timeout":      "2",
+		"application_name":     "gaussdb exporter",
+		"target_session_attrs": "read-write",
+	}
+	if !reflect.DeepEqual(kv, want) {
+		t.Errorf("expected %v, got %v", want, kv)
+	}
+
+	if kv, _ := parseConnString(buildDSN(config.Instance{Host: "db"}, "")); kv["sslmode"] != "disable" {
+		t.Errorf("expected sslmode to default to disable, got %q", kv["sslmode"])
+	}
+}
+
+func TestParseConnString(t *testing.T) {
+	kv, err := parseConnString(` host = db  port=5432 password='a b\'c' user=mon\ itor `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"host": "db", "port": "5432", "password": "a b'c", "user": "mon itor"}
+	if !reflect.DeepEqual(kv, want) {
+		t.Errorf("expected %v, got %v", want, kv)
+	}
+
+	for _, dsn := range []string{"host", "=db", "host=db junk port=1", "password='open"} {
+		if _, err := parseConnString(dsn); err == nil {
+			t.Errorf("expected an error for %q", dsn)
+		}
+	}
+}
+
+func TestParseFingerprint(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"host=db port=5433 password='with space'":    "db:5433",
+		"postgresql://monitor:secret@db:5434/school": "db:5434",
+		"user=monitor": "localhost:5432",
+	} {
+		got, err := parseFingerprint(dsn)
+		if err != nil {
+			t.Errorf("%q: %s", dsn, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: expected %q, got %q", dsn, want, got)
+		}
+	}
+}
+
+func TestLoggableDSN(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"host=db password='it''s'":                   "could not parse DATA_SOURCE_NAME",
+		"host=db user=monitor password='a b'":        "host='db' password='PASSWORD_REMOVED' user='monitor'",
+		"postgresql://monitor:secret@db:5432/school": "postgresql://monitor:PASSWORD_REMOVED@db:5432/school",
+	} {
+		got := loggableDSN(dsn)
+		if got != want {
+			t.Errorf("%q: expected %q, got %q", dsn, want, got)
+		}
+		if strings.Contains(got, "secret") || strings.Contains(got, "a b") {
+			t.Errorf("%q: password leaked in %q", dsn, got)
+		}
+	}
+}
@@ -1,34 +1,188 @@
-package utils
-
-import (
-	"time"
-
-	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
-	"github.com/sirupsen/logrus"
-)
-
-var (
-	//日志模块
-	//日志记录器
-	logger *logrus.Logger
-)
-
-func GetLogger() *logrus.Logger {
-	if logger == nil {
-		path := "logs/gaussdb_exporter_%Y%m%d.log"
-		writer, _ := rotatelogs.New(
-			//路径名称至关重要，rotatelogs通过该路径名匹配来生成新文件名称的，如果粒度太粗则无法生成新文件
-			path,
-			//日志保留时长7天
-			//rotatelogs.WithRotationCount(7),
-			rotatelogs.WithMaxAge(24*time.Duration(7)*time.Hour),
-			//24小时轮滚一次
-			rotatelogs.WithRotationTime(time.Hour*24),
-		)
-		logger = logrus.New()
-		logger.SetOutput(writer)
-		logger.SetLevel(logrus.InfoLevel)
-		logger.SetFormatter(&logrus.TextFormatter{TimestampFormat: "2006-01-02 15:04:05"})
-	}
-	return logger
-}
+package utils
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"github.com/sirupsen/logrus"
+)
+
+// Options configures the logger returned by GetLogger.
+type Options struct {
+	// Level is a logrus level name such as "info" or "warn".
+	Level string
+	// Format is "json" for JSON lines, anything else gives plain text.
+	Format string
+	// Dir is the directory the log files are written to.
+	Dir string
+	// MaxAge is how long rotated log files are kept.
+	MaxAge time.Duration
+	// RotationTime is how often a new log file is started.
+	RotationTime time.Duration
+}
+
+// DefaultOptions are used until Configure is called.
+var DefaultOptions = Options{
+	Level:        "info",
+	Format:       "text",
+	Dir:          "logs",
+	MaxAge:       7 * 24 * time.Hour,
+	RotationTime: 24 * time.Hour,
+}
+
+// Logger is the structured logger shared by every part of the exporter. Log
+// methods take a message followed by alternating keys and values, which are
+// written as separate fields.
+type Logger struct {
+	entry *logrus.Entry
+}
+
+var (
+	//日志模块
+	//日志记录器
+	logger     *logrus.Logger
+	root       *Logger
+	loggerOnce sync.Once
+	configMtx  sync.Mutex
+	// current is the applied configuration, writer its log file.
+	current Options
+	writer  io.Closer
+)
+
+// GetLogger returns the root logger. Use With to derive loggers that carry
+// fields such as instance_id, server or collector.
+func GetLogger() *Logger {
+	loggerOnce.Do(func() {
+		logger = logrus.New()
+		root = &Logger{entry: logrus.NewEntry(logger)}
+		if err := apply(DefaultOptions); err != nil {
+			logger.WithError(err).Error("Failed to configure logger")
+		}
+	})
+	return root
+}
+
+// With returns a logger that adds keyvals to every line it writes.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	return &Logger{entry: l.entry.WithFields(fields(keyvals))}
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(logrus.DebugLevel, msg, keyvals)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(logrus.InfoLevel, msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(logrus.WarnLevel, msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(logrus.ErrorLevel, msg, keyvals)
+}
+
+// Log implements the go-kit log.Logger interface, for libraries that log
+// through it. The level and msg keys are taken from keyvals; lines without a
+// level are written at info.
+func (l *Logger) Log(keyvals ...interface{}) error {
+	level := logrus.InfoLevel
+	msg := ""
+	rest := make([]interface{}, 0, len(keyvals))
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 == len(keyvals) {
+			rest = append(rest, keyvals[i])
+			break
+		}
+		switch fmt.Sprint(keyvals[i]) {
+		case "level":
+			if parsed, err := logrus.ParseLevel(fmt.Sprint(keyvals[i+1])); err == nil {
+				level = parsed
+			}
+		case "msg":
+			msg = fmt.Sprint(keyvals[i+1])
+		default:
+			rest = append(rest, keyvals[i], keyvals[i+1])
+		}
+	}
+	l.log(level, msg, rest)
+	return nil
+}
+
+func (l *Logger) log(level logrus.Level, msg string, keyvals []interface{}) {
+	if !l.entry.Logger.IsLevelEnabled(level) {
+		return
+	}
+	l.entry.WithFields(fields(keyvals)).Log(level, msg)
+}
+
+// fields turns alternating keys and values into logrus fields. A trailing
+// key without a value is kept under "extra" rather than dropped.
+func fields(keyvals []interface{}) logrus.Fields {
+	f := make(logrus.Fields, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 == len(keyvals) {
+			f["extra"] = keyvals[i]
+			break
+		}
+		f[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+	}
+	return f
+}
+
+// Configure applies o to the logger. It can be called again at any time, for
+// example after the config has been reloaded.
+func Configure(o Options) error {
+	GetLogger()
+	return apply(o)
+}
+
+func apply(o Options) error {
+	configMtx.Lock()
+	defer configMtx.Unlock()
+
+	level, err := logrus.ParseLevel(o.Level)
+	if err != nil {
+		return err
+	}
+	if o.MaxAge <= 0 || o.RotationTime <= 0 {
+		return fmt.Errorf("log max age and rotation time must be positive")
+	}
+
+	// Only open a new file writer if the file layout changed.
+	if writer == nil || o.Dir != current.Dir || o.MaxAge != current.MaxAge || o.RotationTime != current.RotationTime {
+		//路径名称至关重要，rotatelogs通过该路径名匹配来生成新文件名称的，如果粒度太粗则无法生成新文件
+		pattern := "gaussdb_exporter_%Y%m%d.log"
+		if o.RotationTime < 24*time.Hour {
+			pattern = "gaussdb_exporter_%Y%m%d%H%M.log"
+		}
+		w, err := rotatelogs.New(
+			filepath.Join(o.Dir, pattern),
+			rotatelogs.WithMaxAge(o.MaxAge),
+			rotatelogs.WithRotationTime(o.RotationTime),
+		)
+		if err != nil {
+			return err
+		}
+		logger.SetOutput(w)
+		if writer != nil {
+			writer.Close()
+		}
+		writer = w
+	}
+
+	logger.SetLevel(level)
+	if strings.EqualFold(o.Format, "json") {
+		logger.SetFormatter(&logrus.JSONFormatter{TimestampFormat: "2006-01-02 15:04:05"})
+	} else {
+		logger.SetFormatter(&logrus.TextFormatter{TimestampFormat: "2006-01-02 15:04:05"})
+	}
+	current = o
+	return nil
+}